@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// artboard is a named export region on the infinite canvas.
+type artboard struct {
+	Name string
+	Rect image.Rectangle
+}
+
+func copyArtboards(src []artboard) []artboard {
+	out := make([]artboard, len(src))
+	copy(out, src)
+	return out
+}
+
+func (g *Game) handleArtboardTool(mx, my int, leftPressed, justClicked bool) {
+	pos := g.worldFromScreen(mx, my)
+	p := image.Pt(int(pos.X), int(pos.Y))
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyDelete) {
+		for i := len(g.artboards) - 1; i >= 0; i-- {
+			if rectContainsPoint(g.artboards[i].Rect, p) {
+				g.artboards = append(g.artboards[:i], g.artboards[i+1:]...)
+				g.recordState()
+				break
+			}
+		}
+	}
+
+	if g.snapToGrid {
+		s := g.snapPoint(pos)
+		p = image.Pt(int(math.Round(float64(s.X))), int(math.Round(float64(s.Y))))
+	}
+	if justClicked {
+		g.boardDrag = &image.Rectangle{Min: p, Max: p}
+		return
+	}
+	if g.boardDrag == nil {
+		return
+	}
+	if leftPressed {
+		g.boardDrag.Max = g.constrainBoard(g.boardDrag.Min, p)
+		return
+	}
+
+	rect := g.boardDrag.Canon()
+	g.boardDrag = nil
+	if rect.Dx() < 8 || rect.Dy() < 8 {
+		return
+	}
+	g.artboards = append(g.artboards, artboard{Name: g.nextArtboardName(), Rect: rect})
+	g.recordState()
+}
+
+// aspectRatio locks artboard drags to W:H. A zero ratio leaves them free.
+type aspectRatio struct {
+	W, H int
+}
+
+func (r aspectRatio) String() string {
+	if r.W == 0 || r.H == 0 {
+		return "Free"
+	}
+	return fmt.Sprintf("%d:%d", r.W, r.H)
+}
+
+var defaultAspectRatios = []aspectRatio{{}, {1, 1}, {4, 3}, {3, 2}, {16, 9}}
+
+func parseAspectRatio(s string) (aspectRatio, error) {
+	var r aspectRatio
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &r.W, &r.H); err != nil {
+		return aspectRatio{}, fmt.Errorf("want W:H, got %q", s)
+	}
+	if r.W <= 0 || r.H <= 0 {
+		return aspectRatio{}, fmt.Errorf("ratio %q must be positive", s)
+	}
+	return r, nil
+}
+
+// buildAspectRatios appends the config's custom ratios to the presets.
+func buildAspectRatios(custom []string) []aspectRatio {
+	ratios := append([]aspectRatio(nil), defaultAspectRatios...)
+	for _, s := range custom {
+		r, err := parseAspectRatio(s)
+		if err != nil {
+			fmt.Println("Ignoring artboard ratio:", err)
+			continue
+		}
+		ratios = append(ratios, r)
+	}
+	return ratios
+}
+
+func (g *Game) boardRatio() aspectRatio {
+	return g.aspectRatios[g.ratioIndex]
+}
+
+func (g *Game) cycleBoardRatio() {
+	g.ratioIndex = (g.ratioIndex + 1) % len(g.aspectRatios)
+}
+
+// constrainBoard returns the drag corner for a rectangle from start toward
+// p that keeps the selected ratio, growing along whichever axis the cursor
+// has moved further.
+func (g *Game) constrainBoard(start, p image.Point) image.Point {
+	r := g.boardRatio()
+	if r.W == 0 || r.H == 0 {
+		return p
+	}
+	dx, dy := p.X-start.X, p.Y-start.Y
+	w, h := abs(dx), abs(dy)
+	if w*r.H > h*r.W {
+		h = w * r.H / r.W
+	} else {
+		w = h * r.W / r.H
+	}
+	if dx < 0 {
+		w = -w
+	}
+	if dy < 0 {
+		h = -h
+	}
+	return image.Pt(start.X+w, start.Y+h)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func (g *Game) nextArtboardName() string {
+	taken := map[string]bool{}
+	for _, a := range g.artboards {
+		taken[a.Name] = true
+	}
+	for n := len(g.artboards) + 1; ; n++ {
+		name := fmt.Sprintf("Artboard %d", n)
+		if !taken[name] {
+			return name
+		}
+	}
+}
+
+// exportArtboards writes every artboard to its own PNG in the last used
+// save directory, re-rendered from the strokes within its bounds.
+func (g *Game) exportArtboards() {
+	if len(g.artboards) == 0 {
+		fmt.Println("No artboards to export")
+		return
+	}
+	dir := g.save.directory
+	if dir == "" {
+		dir = defaultSaveDirectory()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Println("Failed to create directory:", err)
+		return
+	}
+	jobs := make([]exportJob, 0, len(g.artboards))
+	for _, a := range g.artboards {
+		name := strings.ToLower(strings.ReplaceAll(a.Name, " ", "_")) + ".png"
+		jobs = append(jobs, exportJob{path: filepath.Join(dir, name), img: g.renderRegion(a.Rect, 1, false)})
+	}
+	g.writeImages(jobs, g.exportOptions(), func(err error) {
+		g.showNotice("Artboard export failed: " + err.Error())
+	})
+}
+
+func (g *Game) drawArtboards(screen *ebiten.Image) {
+	frame := color.RGBA{230, 180, 90, 255}
+	k := float32(g.zoom)
+	for _, a := range g.artboards {
+		x, y := g.screenFromWorld(float64(a.Rect.Min.X), float64(a.Rect.Min.Y))
+		vector.StrokeRect(screen, x, y, float32(a.Rect.Dx())*k, float32(a.Rect.Dy())*k, 1.5, frame, false)
+		drawText(screen, a.Name, int(x), int(y)-6, frame)
+	}
+	if g.boardDrag != nil {
+		r := g.boardDrag.Canon()
+		x, y := g.screenFromWorld(float64(r.Min.X), float64(r.Min.Y))
+		vector.StrokeRect(screen, x, y, float32(r.Dx())*k, float32(r.Dy())*k, 1, color.RGBA{230, 180, 90, 160}, false)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// noticeDuration is how long showNotice's message stays up.
+const noticeDuration = 8 * time.Second
+
+// backgroundTask is slow file work (encoding, decoding, disk I/O) running
+// off the game goroutine. The work returns an optional finisher that is
+// run back on the game goroutine, where it may touch Game state.
+type backgroundTask struct {
+	label string
+	done  chan func()
+}
+
+// startTask runs work in the background while a spinner is shown. Only
+// one task runs at a time; it reports false if another is still going.
+func (g *Game) startTask(label string, work func() func()) bool {
+	if g.task != nil {
+		fmt.Println("Still busy:", g.task.label)
+		return false
+	}
+	t := &backgroundTask{label: label, done: make(chan func(), 1)}
+	g.task = t
+	go func() { t.done <- work() }()
+	return true
+}
+
+// pollTask runs the finisher of a completed task. While a task is running
+// it keeps frames coming so the spinner animates.
+func (g *Game) pollTask() {
+	if g.task == nil {
+		return
+	}
+	g.markDirty()
+	select {
+	case finish := <-g.task.done:
+		g.task = nil
+		if finish != nil {
+			finish()
+		}
+	default:
+	}
+}
+
+// exportJob is one image to encode and write.
+type exportJob struct {
+	path string
+	img  image.Image
+}
+
+// writeImages encodes and writes the images in the background. If one
+// fails the rest are skipped, and failed is called with the error back on
+// the game goroutine.
+func (g *Game) writeImages(jobs []exportJob, opts exportOptions, failed func(error)) bool {
+	return g.startTask("Saving", func() func() {
+		for _, job := range jobs {
+			if err := writeImage(job.path, job.img, opts); err != nil {
+				return func() { failed(err) }
+			}
+			fmt.Println("Saved to", job.path)
+		}
+		return nil
+	})
+}
+
+// saveFailed reports a save from the dialog that failed after the dialog
+// closed. The dialog reopens with its folder and filename as they were
+// and the error shown in it.
+func (g *Game) saveFailed(err error) {
+	fmt.Println("Failed to save:", err)
+	g.save.failure = err.Error()
+	g.save.visible = true
+	// The folder may have turned out to be read-only.
+	g.save.checkedDir = ""
+}
+
+// showNotice puts a message in the bottom strip for a few seconds, for
+// failures that have no dialog to report them in.
+func (g *Game) showNotice(msg string) {
+	fmt.Println(msg)
+	g.notice = msg
+	g.noticeUntil = time.Now().Add(noticeDuration)
+}
+
+func (g *Game) drawBusy(screen *ebiten.Image) {
+	if g.task == nil {
+		return
+	}
+	w, h := screen.Size()
+	boxW, boxH := float32(200), float32(56)
+	x := (float32(w) - boxW) / 2
+	y := float32(h) - boxH - 24
+	vector.DrawFilledRect(screen, x, y, boxW, boxH, color.RGBA{30, 30, 30, 230}, false)
+
+	// Eight dots fading around a circle, rotating about once a second.
+	const dots = 8
+	cx, cy := x+28, y+boxH/2
+	phase := float64(time.Now().UnixMilli()%1000) / 1000 * dots
+	for i := 0; i < dots; i++ {
+		angle := float64(i) / dots * 2 * math.Pi
+		age := math.Mod(phase-float64(i)+dots, dots) / dots
+		alpha := uint8(255 * (1 - age))
+		dx := float32(math.Cos(angle) * 12)
+		dy := float32(math.Sin(angle) * 12)
+		vector.DrawFilledCircle(screen, cx+dx, cy+dy, 2.5, color.RGBA{alpha, alpha, alpha, alpha}, true)
+	}
+	drawText(screen, g.task.label+"...", int(x)+56, int(cy)+5, color.White)
+}
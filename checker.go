@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// checkerConfig sets the pattern drawn behind a transparent background.
+// It is only ever shown on screen, never exported.
+type checkerConfig struct {
+	Size  int    `json:"size"`
+	Light string `json:"light"`
+	Dark  string `json:"dark"`
+}
+
+// checkerboard is the parsed pattern plus a screen-sized image of it,
+// rebuilt when the window size changes.
+type checkerboard struct {
+	size        int
+	light, dark color.Color
+	img         *ebiten.Image
+}
+
+func newCheckerboard(cfg *checkerConfig) *checkerboard {
+	c := &checkerboard{size: 8, light: color.RGBA{204, 204, 204, 255}, dark: color.RGBA{153, 153, 153, 255}}
+	if cfg == nil {
+		return c
+	}
+	if cfg.Size > 0 {
+		c.size = cfg.Size
+	}
+	for _, f := range []struct {
+		hex string
+		dst *color.Color
+	}{{cfg.Light, &c.light}, {cfg.Dark, &c.dark}} {
+		if f.hex == "" {
+			continue
+		}
+		clr, err := parseHex(f.hex)
+		if err != nil {
+			fmt.Println("Ignoring checker color:", err)
+			continue
+		}
+		*f.dst = clr
+	}
+	return c
+}
+
+// draw fills screen with the pattern, anchored at the top-left corner.
+func (c *checkerboard) draw(screen *ebiten.Image) {
+	b := screen.Bounds()
+	if c.img == nil || c.img.Bounds() != b {
+		if c.img != nil {
+			c.img.Dispose()
+		}
+		c.img = ebiten.NewImage(b.Dx(), b.Dy())
+		c.img.Fill(c.light)
+		cell := ebiten.NewImage(c.size, c.size)
+		cell.Fill(c.dark)
+		for y := 0; y < b.Dy(); y += c.size {
+			for x := (y / c.size % 2) * c.size; x < b.Dx(); x += 2 * c.size {
+				op := &ebiten.DrawImageOptions{}
+				op.GeoM.Translate(float64(x), float64(y))
+				c.img.DrawImage(cell, op)
+			}
+		}
+		cell.Dispose()
+	}
+	screen.DrawImage(c.img, nil)
+}
+
+func isTransparent(c color.Color) bool {
+	_, _, _, a := c.RGBA()
+	return a == 0
+}
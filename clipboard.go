@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands lists the external tools tried, in order, to read the
+// system clipboard. Ebiten has no clipboard API, so this shells out rather
+// than pulling in a cgo dependency.
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbpaste"}}
+	case "windows":
+		return [][]string{{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}}
+	default:
+		return [][]string{
+			{"wl-paste", "--no-newline"},
+			{"xclip", "-selection", "clipboard", "-o"},
+			{"xsel", "--clipboard", "--output"},
+		}
+	}
+}
+
+var errNoClipboard = errors.New("no clipboard tool available")
+
+func readClipboard() (string, error) {
+	for _, args := range clipboardCommands() {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		out, err := exec.Command(args[0], args[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", args[0], err)
+		}
+		return string(out), nil
+	}
+	return "", errNoClipboard
+}
+
+// pasteText inserts clipboard text into the text box being edited or, in
+// text mode, places it as a new text box at the center of the view.
+func (g *Game) pasteText(viewW, viewH int) {
+	clip, err := readClipboard()
+	if err != nil {
+		fmt.Println("Paste unavailable:", err)
+		return
+	}
+	clip = strings.ReplaceAll(clip, "\r\n", "\n")
+	clip = strings.TrimRight(clip, "\n")
+	if clip == "" {
+		return
+	}
+
+	if g.editingText >= 0 && g.editingText < len(g.textBoxes) {
+		g.textBoxes[g.editingText].Text += clip
+		g.rebuildCanvas()
+		return
+	}
+	if g.mode != modeText {
+		return
+	}
+	pos := g.worldFromScreen(viewW/2, (viewH+uiHeight)/2)
+	g.textBoxes = append(g.textBoxes, textBox{Position: pos, Text: clip, Size: g.textSize})
+	g.selectedText = len(g.textBoxes) - 1
+	g.rebuildCanvas()
+	g.recordState()
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	pickerSVSize  = 200
+	pickerHueW    = 24
+	pickerPadding = 12
+)
+
+// colorPicker is an HSV popup: a saturation/value square for the current
+// hue next to a vertical hue strip.
+type colorPicker struct {
+	visible  bool
+	origin   image.Point
+	h, s, v  float64
+	dragging int // 0 none, 1 square, 2 hue strip
+	svImage  *ebiten.Image
+	hueImage *ebiten.Image
+	svHue    float64
+}
+
+func (p *colorPicker) bounds() image.Rectangle {
+	w := pickerPadding*3 + pickerSVSize + pickerHueW
+	h := pickerPadding*2 + pickerSVSize + 36
+	return image.Rect(p.origin.X, p.origin.Y, p.origin.X+w, p.origin.Y+h)
+}
+
+func (p *colorPicker) svRect() image.Rectangle {
+	x := p.origin.X + pickerPadding
+	y := p.origin.Y + pickerPadding
+	return image.Rect(x, y, x+pickerSVSize, y+pickerSVSize)
+}
+
+func (p *colorPicker) hueRect() image.Rectangle {
+	x := p.origin.X + pickerPadding*2 + pickerSVSize
+	y := p.origin.Y + pickerPadding
+	return image.Rect(x, y, x+pickerHueW, y+pickerSVSize)
+}
+
+func (p *colorPicker) color() color.Color {
+	return hsvToRGB(p.h, p.s, p.v)
+}
+
+func (g *Game) toggleColorPicker() {
+	if g.picker.visible {
+		g.picker.visible = false
+		return
+	}
+	g.picker.visible = true
+	g.picker.origin = image.Pt(820, uiHeight+10)
+	g.picker.h, g.picker.s, g.picker.v = rgbToHSV(g.brushColor)
+}
+
+// handleColorPickerInput consumes pointer input aimed at the picker and
+// reports whether the rest of the frame should be skipped.
+func (g *Game) handleColorPickerInput(mx, my int, leftPressed, justClicked bool) bool {
+	p := &g.picker
+	pt := image.Pt(mx, my)
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		p.visible = false
+		return true
+	}
+	if !leftPressed {
+		p.dragging = 0
+	}
+	if justClicked {
+		switch {
+		case rectContainsPoint(p.svRect(), pt):
+			p.dragging = 1
+		case rectContainsPoint(p.hueRect(), pt):
+			p.dragging = 2
+		case rectContainsPoint(p.bounds(), pt):
+		default:
+			if my <= uiHeight {
+				return false
+			}
+			// Swallow the click that dismisses the picker.
+			p.visible = false
+			g.ignoreInput = true
+			return true
+		}
+	}
+
+	switch p.dragging {
+	case 1:
+		r := p.svRect()
+		p.s = clamp01(float64(mx-r.Min.X) / float64(r.Dx()))
+		p.v = 1 - clamp01(float64(my-r.Min.Y)/float64(r.Dy()))
+		g.brushColor = p.color()
+	case 2:
+		r := p.hueRect()
+		p.h = clamp01(float64(my-r.Min.Y)/float64(r.Dy())) * 360
+		if p.h >= 360 {
+			p.h = 359.9
+		}
+		g.brushColor = p.color()
+	}
+	return p.dragging != 0 || rectContainsPoint(p.bounds(), pt)
+}
+
+func (g *Game) drawColorPicker(dst *ebiten.Image) {
+	p := &g.picker
+	b := p.bounds()
+	vector.DrawFilledRect(dst, float32(b.Min.X), float32(b.Min.Y), float32(b.Dx()), float32(b.Dy()), color.RGBA{30, 30, 30, 245}, false)
+
+	if p.hueImage == nil {
+		p.hueImage = ebiten.NewImage(pickerHueW, pickerSVSize)
+		pix := make([]byte, 4*pickerHueW*pickerSVSize)
+		for y := 0; y < pickerSVSize; y++ {
+			c := hsvToRGB(float64(y)/pickerSVSize*360, 1, 1).(color.RGBA)
+			for x := 0; x < pickerHueW; x++ {
+				i := 4 * (y*pickerHueW + x)
+				pix[i], pix[i+1], pix[i+2], pix[i+3] = c.R, c.G, c.B, 255
+			}
+		}
+		p.hueImage.WritePixels(pix)
+	}
+	if p.svImage == nil || p.svHue != p.h {
+		if p.svImage == nil {
+			p.svImage = ebiten.NewImage(pickerSVSize, pickerSVSize)
+		}
+		pix := make([]byte, 4*pickerSVSize*pickerSVSize)
+		for y := 0; y < pickerSVSize; y++ {
+			for x := 0; x < pickerSVSize; x++ {
+				c := hsvToRGB(p.h, float64(x)/pickerSVSize, 1-float64(y)/pickerSVSize).(color.RGBA)
+				i := 4 * (y*pickerSVSize + x)
+				pix[i], pix[i+1], pix[i+2], pix[i+3] = c.R, c.G, c.B, 255
+			}
+		}
+		p.svImage.WritePixels(pix)
+		p.svHue = p.h
+	}
+
+	sv := p.svRect()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(sv.Min.X), float64(sv.Min.Y))
+	dst.DrawImage(p.svImage, op)
+	hue := p.hueRect()
+	op = &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(hue.Min.X), float64(hue.Min.Y))
+	dst.DrawImage(p.hueImage, op)
+
+	cx := float32(sv.Min.X) + float32(p.s)*pickerSVSize
+	cy := float32(sv.Min.Y) + float32(1-p.v)*pickerSVSize
+	vector.StrokeCircle(dst, cx, cy, 6, 2, color.White, true)
+	hy := float32(hue.Min.Y) + float32(p.h/360)*pickerSVSize
+	vector.StrokeRect(dst, float32(hue.Min.X)-2, hy-2, pickerHueW+4, 4, 2, color.White, false)
+
+	vector.DrawFilledRect(dst, float32(sv.Min.X), float32(sv.Max.Y+8), 60, 22, p.color(), false)
+	c := p.color().(color.RGBA)
+	drawText(dst, rgbHex(c), sv.Min.X+72, sv.Max.Y+25, color.White)
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func rgbHex(c color.RGBA) string {
+	const digits = "0123456789ABCDEF"
+	out := []byte{'#'}
+	for _, v := range []uint8{c.R, c.G, c.B} {
+		out = append(out, digits[v>>4], digits[v&0x0f])
+	}
+	return string(out)
+}
+
+// hsvToRGB converts hue in degrees and saturation/value in [0,1] to an
+// opaque RGBA color.
+func hsvToRGB(h, s, v float64) color.Color {
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	to8 := func(f float64) uint8 { return uint8(math.Round((f + m) * 255)) }
+	return color.RGBA{to8(r), to8(g), to8(b), 255}
+}
+
+func rgbToHSV(clr color.Color) (h, s, v float64) {
+	r16, g16, b16, _ := clr.RGBA()
+	r, g, b := float64(r16)/0xffff, float64(g16)/0xffff, float64(b16)/0xffff
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	delta := maxC - minC
+	v = maxC
+	if maxC > 0 {
+		s = delta / maxC
+	}
+	switch {
+	case delta == 0:
+		h = 0
+	case maxC == r:
+		h = 60 * math.Mod((g-b)/delta, 6)
+	case maxC == g:
+		h = 60 * ((b-r)/delta + 2)
+	default:
+		h = 60 * ((r-g)/delta + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+	return h, s, v
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// appConfig is the user configuration stored as JSON in the platform's
+// config directory. Missing fields keep their defaults.
+type appConfig struct {
+	Keys map[string]string `json:"keys"`
+	// HistoryLimit caps how many undo snapshots are kept.
+	HistoryLimit int `json:"historyLimit"`
+	// ClickDebounce is how long, in milliseconds, the file dialog ignores
+	// presses after handling a click, so one physical click cannot both
+	// navigate and then act on the re-laid-out list.
+	ClickDebounce int `json:"clickDebounce"`
+	// ArtboardRatios adds custom "W:H" aspect ratios to the artboard presets.
+	ArtboardRatios []string `json:"artboardRatios,omitempty"`
+	// Tools holds the toolbar state from the last session.
+	Tools *toolSettings `json:"tools,omitempty"`
+	// Scroll is "zoom" (wheel zooms) or "pan" (scrolling pans, Ctrl zooms).
+	Scroll string `json:"scroll,omitempty"`
+	// Checker styles the pattern shown behind a transparent background.
+	Checker *checkerConfig `json:"checker,omitempty"`
+}
+
+func configPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil || dir == "" {
+		dir = defaultSaveDirectory()
+	}
+	return filepath.Join(dir, "draftit", "config.json")
+}
+
+// loadConfig reads the config file. A missing file is written out with
+// the defaults so users have something to edit; a malformed one is
+// reported and ignored. Key bindings equal to a default are dropped, so
+// buildKeymap supplies the current default instead.
+func loadConfig() appConfig {
+	cfg := defaultConfig()
+	path := configPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := saveConfig(cfg); err != nil {
+			fmt.Println("Failed to write config:", err)
+		}
+		return cfg
+	}
+	if err != nil {
+		fmt.Println("Failed to read config:", err)
+		return cfg
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		fmt.Println("Ignoring malformed config", path+":", err)
+		return defaultConfig()
+	}
+	pruneDefaultKeys(cfg.Keys)
+	return cfg
+}
+
+// updateConfig re-reads the config file, applies fn, and writes it back,
+// keeping anything edited since startup. It refuses to touch a malformed
+// file.
+func updateConfig(fn func(*appConfig)) error {
+	cfg := defaultConfig()
+	data, err := os.ReadFile(configPath())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("config is malformed: %w", err)
+		}
+	}
+	fn(&cfg)
+	return saveConfig(cfg)
+}
+
+// saveConfig writes cfg to the config file, keeping only the key bindings
+// that differ from the defaults.
+func saveConfig(cfg appConfig) error {
+	pruneDefaultKeys(cfg.Keys)
+	if cfg.Keys == nil {
+		cfg.Keys = map[string]string{}
+	}
+	path := configPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// defaultConfig leaves Keys empty: the default bindings live in
+// defaultKeyBindings, so changing one there reaches existing users.
+func defaultConfig() appConfig {
+	return appConfig{Keys: map[string]string{}, HistoryLimit: 100, ClickDebounce: 140}
+}
+
+// retiredKeyBindings lists former default bindings per action. Config
+// files from before a default changed hold the old one, which is dropped
+// like a current default so those users get the new binding.
+var retiredKeyBindings = map[string][]string{
+	actionRedo: {"Ctrl+R"},
+}
+
+// pruneDefaultKeys removes the bindings in keys that match the action's
+// default, current or retired.
+func pruneDefaultKeys(keys map[string]string) {
+	for action, spec := range keys {
+		if sameBindings(spec, defaultKeyBindings[action]) {
+			delete(keys, action)
+			continue
+		}
+		for _, old := range retiredKeyBindings[action] {
+			if sameBindings(spec, old) {
+				delete(keys, action)
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestPruneDefaultKeys(t *testing.T) {
+	keys := map[string]string{
+		actionUndo:    "ctrl+z",         // the default, spelled differently
+		actionRedo:    "Ctrl+R",         // the default before Ctrl+Y was added
+		actionPalette: "Ctrl+Shift+P",   // a real change
+		actionGrid:    "Ctrl+Nonsense",  // does not parse, kept for the warning
+		"unknown":     "F5",             // not an action, kept for the warning
+		actionStats:   "F2, Ctrl+Alt+S", // default plus an extra key
+	}
+	pruneDefaultKeys(keys)
+	want := map[string]string{
+		actionPalette: "Ctrl+Shift+P",
+		actionGrid:    "Ctrl+Nonsense",
+		"unknown":     "F5",
+		actionStats:   "F2, Ctrl+Alt+S",
+	}
+	if !maps.Equal(keys, want) {
+		t.Errorf("pruned keys = %v, want %v", keys, want)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// sampleCanvas returns the color of the canvas under screen position
+// (mx, my). Points past the edge of the canvas read as the background;
+// ok is false when there is nothing opaque enough to pick.
+func (g *Game) sampleCanvas(mx, my int) (color.Color, bool) {
+	p := g.worldFromScreen(mx, my)
+	pt := image.Pt(int(math.Floor(float64(p.X))), int(math.Floor(float64(p.Y))))
+	rect := g.canvasRect()
+	if !pt.In(rect) {
+		if isTransparent(g.bgColor) {
+			return nil, false
+		}
+		return g.bgColor, true
+	}
+	local := pt.Sub(rect.Min)
+	px := readImage(g.canvas.SubImage(image.Rect(local.X, local.Y, local.X+1, local.Y+1)).(*ebiten.Image))
+	c := color.NRGBAModel.Convert(px.RGBAAt(0, 0)).(color.NRGBA)
+	if c.A == 0 {
+		return nil, false
+	}
+	// Opacity is a separate setting, so the picked color is always solid.
+	c.A = 255
+	return c, true
+}
+
+// eyedropperTool sets the brush color from the canvas on left click and
+// the secondary color on right click.
+type eyedropperTool struct{}
+
+func (eyedropperTool) Name() string { return "Eyedropper" }
+
+func (eyedropperTool) OnPress(g *Game, in toolInput) {
+	c, ok := g.sampleCanvas(in.mx, in.my)
+	if !ok {
+		fmt.Println("Nothing to sample here")
+		return
+	}
+	if !in.left && in.right {
+		g.secondColor = c
+		return
+	}
+	g.brushColor = c
+	if g.picker.visible {
+		g.picker.h, g.picker.s, g.picker.v = rgbToHSV(c)
+	}
+}
+
+func (eyedropperTool) OnDrag(*Game, toolInput) {}
+
+func (eyedropperTool) OnRelease(*Game, toolInput) {}
+
+func (eyedropperTool) OnIdle(*Game, toolInput) {}
+
+func (eyedropperTool) Draw(*Game, *ebiten.Image) {}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const saveEntryHeight = 28
+
+// saveListRect is the save dialog's file list area for a dialog whose
+// top-left corner is at (x, y). Drawing and click handling share it.
+func saveListRect(x, y, dialogW, dialogH int) image.Rectangle {
+	return image.Rect(x+20, y+130, x+dialogW-20, y+dialogH-140)
+}
+
+// listRows is how many entries fit in the list at once.
+func listRows(list image.Rectangle) int {
+	return list.Dy() / saveEntryHeight
+}
+
+// entryAt returns the index of the entry drawn under p, or -1 when p is
+// outside the list or below the last visible entry.
+func (s *saveDialog) entryAt(list image.Rectangle, p image.Point) int {
+	if !p.In(list) {
+		return -1
+	}
+	row := (p.Y - list.Min.Y) / saveEntryHeight
+	idx := s.scroll + row
+	if row >= listRows(list) || idx >= len(s.entries) {
+		return -1
+	}
+	return idx
+}
+
+// scrollList moves the list by the wheel over it, collecting fractional
+// trackpad deltas until they add up to a whole row.
+func (s *saveDialog) scrollList(list image.Rectangle, p image.Point) {
+	_, wy := ebiten.Wheel()
+	if wy == 0 || !p.In(list) {
+		return
+	}
+	s.wheel -= wy
+	steps := int(s.wheel)
+	s.wheel -= float64(steps)
+	s.scroll += steps
+	s.clampScroll(listRows(list))
+}
+
+// revealSelected scrolls just enough to show the selected entry.
+func (s *saveDialog) revealSelected(rows int) {
+	if s.selected >= 0 && s.selected < s.scroll {
+		s.scroll = s.selected
+	}
+	if s.selected >= s.scroll+rows {
+		s.scroll = s.selected - rows + 1
+	}
+	s.clampScroll(rows)
+}
+
+func (s *saveDialog) clampScroll(rows int) {
+	s.scroll = max(0, min(s.scroll, len(s.entries)-rows))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSaveListRect(t *testing.T) {
+	for _, tc := range []struct {
+		x, y, w, h int
+		want       image.Rectangle
+	}{
+		{0, 0, 720, 520, image.Rect(20, 130, 700, 380)},
+		{100, 50, 720, 520, image.Rect(120, 180, 800, 430)},
+	} {
+		if got := saveListRect(tc.x, tc.y, tc.w, tc.h); got != tc.want {
+			t.Errorf("saveListRect(%d, %d, %d, %d) = %v, want %v", tc.x, tc.y, tc.w, tc.h, got, tc.want)
+		}
+	}
+}
+
+func TestEntryAt(t *testing.T) {
+	list := saveListRect(0, 0, 720, 520) // 8 full rows
+	row := func(r int) image.Point {
+		return image.Pt(100, list.Min.Y+r*saveEntryHeight+saveEntryHeight/2)
+	}
+	for _, tc := range []struct {
+		name    string
+		entries int
+		scroll  int
+		p       image.Point
+		want    int
+	}{
+		{"top", 30, 0, row(0), 0},
+		{"top edge", 30, 0, image.Pt(100, list.Min.Y), 0},
+		{"middle", 30, 0, row(3), 3},
+		{"last row", 30, 0, row(7), 7},
+		{"partial row", 30, 0, row(8), -1},
+		{"above", 30, 0, image.Pt(100, list.Min.Y-1), -1},
+		{"left", 30, 0, image.Pt(list.Min.X-1, row(0).Y), -1},
+		{"right edge", 30, 0, image.Pt(list.Max.X, row(0).Y), -1},
+		{"scrolled top", 30, 5, row(0), 5},
+		{"scrolled last row", 30, 5, row(7), 12},
+		{"scrolled to end", 30, 22, row(7), 29},
+		{"scrolled partial row", 30, 22, row(8), -1},
+		{"past short list", 3, 0, row(4), -1},
+		{"end of short list", 3, 0, row(2), 2},
+	} {
+		s := &saveDialog{entries: make([]fileEntry, tc.entries), scroll: tc.scroll}
+		if got := s.entryAt(list, tc.p); got != tc.want {
+			t.Errorf("%s: entryAt(%v) with scroll %d = %d, want %d", tc.name, tc.p, tc.scroll, got, tc.want)
+		}
+	}
+}
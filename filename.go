@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+)
+
+// caretBlinkTicks is how long the filename caret stays on, then off.
+const caretBlinkTicks = 30
+
+// setFilename replaces the filename and moves the caret to its end.
+func (s *saveDialog) setFilename(name string) {
+	s.filename = name
+	s.caret = utf8.RuneCountInString(name)
+}
+
+// editFilename applies typed characters and the editing keys to the
+// filename at the caret. It reports whether anything was typed.
+func (s *saveDialog) editFilename(chars []rune) bool {
+	name := []rune(s.filename)
+	s.caret = max(0, min(s.caret, len(name)))
+	if len(chars) > 0 {
+		name = slices.Insert(name, s.caret, chars...)
+		s.caret += len(chars)
+	}
+	switch {
+	case keyRepeated(ebiten.KeyBackspace) && s.caret > 0:
+		name = slices.Delete(name, s.caret-1, s.caret)
+		s.caret--
+	case keyRepeated(ebiten.KeyDelete) && s.caret < len(name):
+		name = slices.Delete(name, s.caret, s.caret+1)
+	case keyRepeated(ebiten.KeyArrowLeft):
+		s.caret = max(0, s.caret-1)
+	case keyRepeated(ebiten.KeyArrowRight):
+		s.caret = min(len(name), s.caret+1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyHome):
+		s.caret = 0
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnd):
+		s.caret = len(name)
+	}
+	s.filename = string(name)
+	return len(chars) > 0
+}
+
+// typedPath resolves the filename field against the current directory.
+// Absolute paths are taken as typed and a leading "~" means the home
+// directory, so a full path can be typed or pasted in.
+func (s *saveDialog) typedPath() string {
+	name := s.filename
+	if name == "~" || strings.HasPrefix(name, "~/") || strings.HasPrefix(name, "~"+string(filepath.Separator)) {
+		if home, err := os.UserHomeDir(); err == nil {
+			name = filepath.Join(home, name[1:])
+		}
+	}
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(s.directory, name)
+}
+
+// targetWritable reports whether the folder the typed path saves into can
+// be written. A typed subfolder that does not exist yet is created on
+// save, so the nearest existing folder above it is checked instead. The
+// answer is kept until the folder changes, since the dialog asks every
+// frame.
+func (s *saveDialog) targetWritable() bool {
+	dir := s.directory
+	if s.filename != "" {
+		dir = filepath.Dir(s.typedPath())
+	}
+	if dir != s.checkedDir {
+		s.checkedDir = dir
+		s.writable = dirWritable(existingDir(dir))
+	}
+	return s.writable
+}
+
+// existingDir returns dir or, if it does not exist, its nearest ancestor
+// that does.
+func existingDir(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// enterTypedDirectory switches the dialog to the typed path if it names
+// a directory, clearing the field, and reports whether it did.
+func (s *saveDialog) enterTypedDirectory() bool {
+	path := s.typedPath()
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	s.directory = path
+	s.loadEntries()
+	s.setFilename("")
+	return true
+}
+
+// pastedFilename returns the clipboard's first line for the filename
+// field, or nil when there is nothing to paste.
+func pastedFilename() []rune {
+	clip, err := readClipboard()
+	if err != nil {
+		fmt.Println("Paste unavailable:", err)
+		return nil
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(clip), "\n")
+	return []rune(strings.TrimSpace(line))
+}
+
+// keyRepeated reports a key press on the tick it goes down and then at
+// a steady rate while it is held, like typing in a text field.
+func keyRepeated(key ebiten.Key) bool {
+	const delay, interval = 24, 3
+	d := inpututil.KeyPressDuration(key)
+	return d == 1 || d > delay && (d-delay)%interval == 0
+}
+
+// drawFilenameCaret draws the blinking caret after the caret's rune, with
+// the filename text starting at x.
+func (g *Game) drawFilenameCaret(dst *ebiten.Image, x, baseline int) {
+	if uiFont == nil || g.save.listFocus || (g.frame/caretBlinkTicks)%2 == 1 {
+		return
+	}
+	runes := []rune(g.save.filename)
+	prefix := string(runes[:max(0, min(g.save.caret, len(runes)))])
+	cx := float32(x + font.MeasureString(uiFont, prefix).Round())
+	vector.StrokeLine(dst, cx, float32(baseline-14), cx, float32(baseline+4), 1, color.White, false)
+}
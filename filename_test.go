@@ -0,0 +1,20 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTargetWritable(t *testing.T) {
+	dir := t.TempDir()
+	s := &saveDialog{directory: dir}
+	for _, name := range []string{"", "out.png", filepath.Join("new", "deeper", "out.png"), filepath.Join(dir, "abs", "out.png")} {
+		s.setFilename(name)
+		if !s.targetWritable() {
+			t.Errorf("%q: target folder %s reported read-only", name, s.checkedDir)
+		}
+	}
+	if got := existingDir(filepath.Join(dir, "new", "deeper")); got != dir {
+		t.Errorf("existingDir = %s, want %s", got, dir)
+	}
+}
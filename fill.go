@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// fillTolerance is how far, per RGBA channel, a pixel may differ from the
+// clicked one and still be filled. It lets fills run up to antialiased
+// edges instead of stopping at the first blended pixel.
+const fillTolerance = 48
+
+// fillRegion is the pixel coverage of a paint-bucket fill. Mask.Rect is in
+// world pixels. A region is never changed once made, so undo snapshots
+// share it.
+type fillRegion struct {
+	Mask *image.Alpha
+	img  *ebiten.Image
+}
+
+// image returns the mask as a white GPU image, uploading it on first use.
+func (f *fillRegion) image() *ebiten.Image {
+	if f.img == nil {
+		f.img = ebiten.NewImageFromImage(f.Mask)
+	}
+	return f.img
+}
+
+func (f *fillRegion) covers(p Vec2) bool {
+	pt := image.Pt(int(math.Floor(float64(p.X))), int(math.Floor(float64(p.Y))))
+	return pt.In(f.Mask.Rect) && f.Mask.AlphaAt(pt.X, pt.Y).A != 0
+}
+
+// transformed returns the region mapped by p*k + off, resampled with
+// nearest-neighbor so the edges stay hard.
+func (f *fillRegion) transformed(k float32, off Vec2) *fillRegion {
+	src := f.Mask.Rect
+	lo := Vec2{X: float32(src.Min.X)*k + off.X, Y: float32(src.Min.Y)*k + off.Y}
+	hi := Vec2{X: float32(src.Max.X)*k + off.X, Y: float32(src.Max.Y)*k + off.Y}
+	dstRect := image.Rect(
+		int(math.Floor(float64(lo.X))), int(math.Floor(float64(lo.Y))),
+		int(math.Ceil(float64(hi.X))), int(math.Ceil(float64(hi.Y))),
+	)
+	mask := image.NewAlpha(dstRect)
+	for y := dstRect.Min.Y; y < dstRect.Max.Y; y++ {
+		for x := dstRect.Min.X; x < dstRect.Max.X; x++ {
+			sx := int(math.Floor(float64((float32(x) + 0.5 - off.X) / k)))
+			sy := int(math.Floor(float64((float32(y) + 0.5 - off.Y) / k)))
+			if image.Pt(sx, sy).In(src) {
+				mask.SetAlpha(x, y, f.Mask.AlphaAt(sx, sy))
+			}
+		}
+	}
+	return &fillRegion{Mask: mask}
+}
+
+// floodMask scanline-fills img from seed, covering the contiguous pixels
+// within tolerance of the seed's color, and returns the result grown by
+// one pixel so it tucks under the antialiased edge around it. The fill
+// never leaves img's bounds.
+func floodMask(img *image.RGBA, seed image.Point, tolerance int) *image.Alpha {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	at := func(x, y int) []uint8 {
+		i := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+		return img.Pix[i : i+4]
+	}
+	target := append([]uint8(nil), at(seed.X, seed.Y)...)
+	filled := make([]bool, w*h)
+	matches := func(x, y int) bool {
+		if filled[y*w+x] {
+			return false
+		}
+		p := at(x, y)
+		for c := range target {
+			if d := int(p[c]) - int(target[c]); d > tolerance || d < -tolerance {
+				return false
+			}
+		}
+		return true
+	}
+
+	box := image.Rectangle{Min: seed, Max: seed.Add(image.Pt(1, 1))}
+	stack := []image.Point{seed}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !matches(p.X, p.Y) {
+			continue
+		}
+		l, r := p.X, p.X
+		for l > 0 && matches(l-1, p.Y) {
+			l--
+		}
+		for r < w-1 && matches(r+1, p.Y) {
+			r++
+		}
+		for x := l; x <= r; x++ {
+			filled[p.Y*w+x] = true
+		}
+		box = box.Union(image.Rect(l, p.Y, r+1, p.Y+1))
+		// Queue one seed per run of matching pixels on the rows above
+		// and below.
+		for _, y := range [2]int{p.Y - 1, p.Y + 1} {
+			if y < 0 || y >= h {
+				continue
+			}
+			inRun := false
+			for x := l; x <= r; x++ {
+				m := matches(x, y)
+				if m && !inRun {
+					stack = append(stack, image.Pt(x, y))
+				}
+				inRun = m
+			}
+		}
+	}
+
+	box = box.Inset(-1).Intersect(image.Rect(0, 0, w, h))
+	mask := image.NewAlpha(box)
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		for x := box.Min.X; x < box.Max.X; x++ {
+			if nearFilled(filled, w, h, x, y) {
+				mask.Pix[mask.PixOffset(x, y)] = 0xff
+			}
+		}
+	}
+	return mask
+}
+
+func nearFilled(filled []bool, w, h, x, y int) bool {
+	for ny := max(y-1, 0); ny <= min(y+1, h-1); ny++ {
+		for nx := max(x-1, 0); nx <= min(x+1, w-1); nx++ {
+			if filled[ny*w+nx] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// floodFill fills the region of the canvas under the cursor with clr. The
+// fill is stored as a stroke holding its coverage mask, so it replays in
+// drawing order on redraws and exports and can be undone or erased like
+// any other stroke.
+func (g *Game) floodFill(mx, my int, clr color.Color) {
+	if g.layerHidden(g.activeLayer) {
+		return
+	}
+	pos := g.worldFromScreen(mx, my)
+	pt := image.Pt(int(math.Floor(float64(pos.X))), int(math.Floor(float64(pos.Y))))
+	rect := g.canvasRect()
+	if !pt.In(rect) {
+		fmt.Println("Nothing to fill outside the canvas")
+		return
+	}
+	mask := floodMask(readImage(g.canvas), pt.Sub(rect.Min), fillTolerance)
+	// Alpha pixels are addressed relative to Rect.Min, so moving the
+	// rectangle into world space leaves them in place.
+	mask.Rect = mask.Rect.Add(rect.Min)
+	s := &stroke{
+		Points:  []Vec2{pos},
+		Color:   clr,
+		Opacity: g.brushOpacity,
+		Layer:   g.activeLayer,
+		Fill:    &fillRegion{Mask: mask},
+	}
+	if g.activeTag != "" {
+		s.Tags = []string{g.activeTag}
+	}
+	s.recomputeBounds()
+	g.strokes = append(g.strokes, s)
+	g.index.insert(s)
+	g.rebuildCanvas()
+	g.recordState()
+}
+
+// addFill paints a fill stroke's mask in its color.
+func (b *strokeBatch) addFill(s *stroke, xf func(Vec2) Vec2, scale float64) {
+	b.flush()
+	clr := s.Color
+	if b.mask {
+		clr = color.White
+	}
+	at := xf(Vec2{X: float32(s.Fill.Mask.Rect.Min.X), Y: float32(s.Fill.Mask.Rect.Min.Y)})
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(float64(at.X), float64(at.Y))
+	op.ColorScale.ScaleWithColor(clr)
+	if !b.mask {
+		op.ColorScale.ScaleAlpha(float32(s.alpha()))
+	}
+	b.dst.DrawImage(s.Fill.image(), op)
+}
+
+// encodeFill stores a fill mask as PNG bytes for project files. The PNG
+// loses the mask's position, which is saved alongside it.
+func encodeFill(f *fillRegion) ([]byte, [2]int, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, f.Mask); err != nil {
+		return nil, [2]int{}, err
+	}
+	return buf.Bytes(), [2]int{f.Mask.Rect.Min.X, f.Mask.Rect.Min.Y}, nil
+}
+
+func decodeFill(data []byte, at [2]int) (*fillRegion, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	mask := image.NewAlpha(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(mask, mask.Rect, img, b.Min, draw.Src)
+	mask.Rect = mask.Rect.Add(image.Pt(at[0], at[1]))
+	return &fillRegion{Mask: mask}, nil
+}
+
+// fillTool floods the clicked region with the brush color, or with the
+// secondary color on right click.
+type fillTool struct{}
+
+func (fillTool) Name() string { return "Fill" }
+
+func (fillTool) OnPress(g *Game, in toolInput) {
+	clr := g.brushColor
+	if !in.left && in.right {
+		clr = g.secondColor
+	}
+	g.floodFill(in.mx, in.my, clr)
+}
+
+func (fillTool) OnDrag(*Game, toolInput) {}
+
+func (fillTool) OnRelease(*Game, toolInput) {}
+
+func (fillTool) OnIdle(*Game, toolInput) {}
+
+func (fillTool) Draw(*Game, *ebiten.Image) {}
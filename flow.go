@@ -0,0 +1,119 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// flowSpacing is the gap between flow stamps as a fraction of the brush
+// width. Stamps overlap several deep, so a stroke's own buildup depends on
+// this as well as on its flow.
+const flowSpacing = 0.2
+
+// flowRate is the share of paint each stamp of s lays down. Zero means
+// full flow so strokes saved before flow existed render as before.
+func (s *stroke) flowRate() float64 {
+	if s.Flow <= 0 || s.Flow > 1 {
+		return 1
+	}
+	return s.Flow
+}
+
+// stamper spaces flow stamps evenly along a path. It carries the distance
+// walked since the last stamp across calls, so a stroke drawn live one
+// segment at a time gets the same stamps as when it is redrawn whole.
+type stamper struct {
+	since float32
+}
+
+// walk stamps from a toward b at width w, not including a itself.
+func (st *stamper) walk(a, b Vec2, w float32, stamp func(Vec2, float32)) {
+	step := max(w*flowSpacing, 0.5)
+	dx, dy := b.X-a.X, b.Y-a.Y
+	dist := float32(math.Hypot(float64(dx), float64(dy)))
+	d := step - st.since
+	for ; d <= dist; d += step {
+		t := d / dist
+		stamp(Vec2{X: a.X + dx*t, Y: a.Y + dy*t}, w)
+	}
+	st.since = dist - (d - step)
+}
+
+// forStamps calls stamp for every flow stamp of s, in world space. It
+// follows the same segments and widths as live drawing: each straight
+// segment at the average of its endpoints' widths, and each piece of a
+// smoothed curve at the width interpolated along it.
+func (s *stroke) forStamps(smooth bool, stamp func(Vec2, float32)) {
+	stamp(s.Points[0], s.widthAt(0))
+	var st stamper
+	var curve []Vec2
+	for i := 0; i+1 < len(s.Points); i++ {
+		wa, wb := s.widthAt(i), s.widthAt(i+1)
+		if !smooth {
+			st.walk(s.Points[i], s.Points[i+1], (wa+wb)/2, stamp)
+			continue
+		}
+		curve = smoothSegment(curve[:0], s.Points, i)
+		prev := s.Points[i]
+		for j, q := range curve {
+			t := (float32(j) + 0.5) / float32(len(curve))
+			st.walk(prev, q, wa+(wb-wa)*t, stamp)
+			prev = q
+		}
+	}
+}
+
+// scaleColor multiplies every premultiplied component of c by k.
+func scaleColor(c color.Color, k float64) color.Color {
+	r, g, b, a := c.RGBA()
+	f := func(v uint32) uint16 { return uint16(float64(v) * k) }
+	return color.RGBA64{R: f(r), G: f(g), B: f(b), A: f(a)}
+}
+
+// addStamps paints s as overlapping discs laid down at its flow, so paint
+// builds up where the stroke, or later strokes, pass over it again.
+func (b *strokeBatch) addStamps(s *stroke, xf func(Vec2) Vec2, scale float64) {
+	b.flush()
+	b.clr = scaleColor(s.Color, s.flowRate())
+	s.forStamps(b.smooth, func(p Vec2, w float32) {
+		b.appendDisc(xf(p), w*float32(scale)/2)
+	})
+	b.flush()
+}
+
+// appendDisc adds a filled circle as a triangle fan. Unlike a filled
+// vector path, the triangles never overlap, so a translucent disc blends
+// evenly.
+func (b *strokeBatch) appendDisc(c Vec2, r float32) {
+	n := max(8, min(48, int(r*2)))
+	if len(b.vertices)+n+1 > math.MaxUint16 {
+		b.flush()
+	}
+	base := uint16(len(b.vertices))
+	b.vertices = append(b.vertices, ebiten.Vertex{DstX: c.X, DstY: c.Y})
+	for i := 0; i < n; i++ {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		b.vertices = append(b.vertices, ebiten.Vertex{DstX: c.X + r*float32(math.Cos(a)), DstY: c.Y + r*float32(math.Sin(a))})
+		b.indices = append(b.indices, base, base+1+uint16(i), base+1+uint16((i+1)%n))
+	}
+}
+
+// drawStamps paints the live stroke's flow stamps from a to b, carrying
+// the spacing over from the previous segment.
+func (g *Game) drawStamps(a, b Vec2, w float32) {
+	batch := &strokeBatch{dst: g.strokeTarget(), clr: scaleColor(g.liveColor(), g.current.flowRate())}
+	g.stamps.walk(a, b, w, func(p Vec2, w float32) {
+		batch.appendDisc(g.worldToCanvas(p), w/2)
+	})
+	batch.flush()
+}
+
+// drawFirstStamp paints the stamp at the start of the live stroke.
+func (g *Game) drawFirstStamp() {
+	g.stamps = stamper{}
+	batch := &strokeBatch{dst: g.strokeTarget(), clr: scaleColor(g.liveColor(), g.current.flowRate())}
+	batch.appendDisc(g.worldToCanvas(g.current.Points[0]), g.current.widthAt(0)/2)
+	batch.flush()
+}
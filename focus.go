@@ -0,0 +1,21 @@
+package main
+
+// toolbarHeight is the height of the toolbar strip that covers the top of
+// the canvas, or zero while the UI is hidden.
+func (g *Game) toolbarHeight() int {
+	if g.hideUI {
+		return 0
+	}
+	return uiHeight
+}
+
+// toggleUI switches distraction-free mode, which hides the toolbar and
+// status strip and gives the whole window to the canvas. Keyboard
+// shortcuts keep working, so tools can still be switched with Tab.
+func (g *Game) toggleUI() {
+	g.hideUI = !g.hideUI
+	g.picker.visible = false
+	for _, s := range g.sliders {
+		s.active = false
+	}
+}
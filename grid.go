@@ -0,0 +1,43 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// gridMinScreenStep is the closest, in screen pixels, grid lines are
+// drawn. Zoomed far out, every other line is skipped until they are at
+// least this far apart.
+const gridMinScreenStep = 6
+
+// drawGrid draws the reference grid over the visible canvas. Lines sit at
+// multiples of gridSize in world coordinates, so they pan and zoom with
+// the drawing.
+func (g *Game) drawGrid(screen *ebiten.Image) {
+	if !g.showGrid || g.gridSize <= 0 {
+		return
+	}
+	w, h := screen.Size()
+	step := g.gridSize
+	for step*g.zoom < gridMinScreenStep {
+		step *= 2
+	}
+	top := g.worldFromScreen(0, g.toolbarHeight())
+	bottom := g.worldFromScreen(w, h)
+	clr := color.RGBA{128, 128, 128, 70}
+	for x := math.Ceil(float64(top.X)/step) * step; x <= float64(bottom.X); x += step {
+		sx, _ := g.screenFromWorld(x, 0)
+		vector.StrokeLine(screen, sx, float32(g.toolbarHeight()), sx, float32(h), 1, clr, false)
+	}
+	for y := math.Ceil(float64(top.Y)/step) * step; y <= float64(bottom.Y); y += step {
+		_, sy := g.screenFromWorld(0, y)
+		vector.StrokeLine(screen, 0, sy, float32(w), sy, 1, clr, false)
+	}
+}
+
+func (g *Game) toggleGrid() {
+	g.showGrid = !g.showGrid
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Action names used as keys in the config file's "keys" map.
+const (
+	actionUndo       = "undo"
+	actionRedo       = "redo"
+	actionPalette    = "palette"
+	actionSwapColors = "swap-colors"
+	actionStraighten = "straighten"
+	actionStats      = "stats"
+	actionPaste      = "paste"
+	actionCycleTool  = "cycle-tool"
+	actionHome       = "home"
+	actionGrid       = "grid"
+	actionHideUI     = "hide-ui"
+	actionSnap       = "snap"
+)
+
+var defaultKeyBindings = map[string]string{
+	actionUndo:       "Ctrl+Z",
+	actionRedo:       "Ctrl+R, Ctrl+Y",
+	actionPalette:    "Ctrl+P",
+	actionSwapColors: "X",
+	actionStraighten: "L",
+	actionStats:      "F2",
+	actionPaste:      "Ctrl+V",
+	actionCycleTool:  "Tab",
+	actionHome:       "Home",
+	actionGrid:       "G",
+	actionHideUI:     "F10",
+	actionSnap:       "Shift+G",
+}
+
+// keyBinding is a key plus the exact modifiers that must accompany it.
+// Ctrl matches either Control or Meta so bindings work on macOS.
+type keyBinding struct {
+	key   ebiten.Key
+	ctrl  bool
+	shift bool
+	alt   bool
+}
+
+// parseKeyBindings reads a comma-separated list of alternative bindings,
+// such as "Ctrl+R, Ctrl+Y".
+func parseKeyBindings(s string) ([]keyBinding, error) {
+	var out []keyBinding
+	for _, part := range strings.Split(s, ",") {
+		b, err := parseKeyBinding(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// parseKeyBinding reads strings such as "Ctrl+Shift+Z" or "F2". Key names
+// follow Ebiten's and are case-insensitive.
+func parseKeyBinding(s string) (keyBinding, error) {
+	var b keyBinding
+	parts := strings.Split(s, "+")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i < len(parts)-1 {
+			switch strings.ToLower(part) {
+			case "ctrl", "cmd", "control", "meta":
+				b.ctrl = true
+			case "shift":
+				b.shift = true
+			case "alt", "option":
+				b.alt = true
+			default:
+				return b, fmt.Errorf("unknown modifier %q", part)
+			}
+			continue
+		}
+		if err := b.key.UnmarshalText([]byte(part)); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+func (b keyBinding) String() string {
+	var parts []string
+	if b.ctrl {
+		parts = append(parts, "Ctrl")
+	}
+	if b.shift {
+		parts = append(parts, "Shift")
+	}
+	if b.alt {
+		parts = append(parts, "Alt")
+	}
+	return strings.Join(append(parts, b.key.String()), "+")
+}
+
+func (b keyBinding) justPressed() bool {
+	ctrl := ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)
+	return inpututil.IsKeyJustPressed(b.key) &&
+		ctrl == b.ctrl &&
+		ebiten.IsKeyPressed(ebiten.KeyShift) == b.shift &&
+		ebiten.IsKeyPressed(ebiten.KeyAlt) == b.alt
+}
+
+// buildKeymap applies the configured bindings over the defaults, warning
+// about entries that do not parse and about actions sharing a binding.
+func buildKeymap(overrides map[string]string) map[string][]keyBinding {
+	keymap := map[string][]keyBinding{}
+	for action, spec := range defaultKeyBindings {
+		b, err := parseKeyBindings(spec)
+		if err != nil {
+			panic(fmt.Errorf("bad default binding for %s: %w", action, err))
+		}
+		keymap[action] = b
+	}
+	for action, spec := range overrides {
+		if _, ok := defaultKeyBindings[action]; !ok {
+			fmt.Printf("Ignoring binding for unknown action %q\n", action)
+			continue
+		}
+		b, err := parseKeyBindings(spec)
+		if err != nil {
+			fmt.Printf("Ignoring binding %q for %s: %v\n", spec, action, err)
+			continue
+		}
+		keymap[action] = b
+	}
+
+	owners := map[keyBinding][]string{}
+	for action, bindings := range keymap {
+		for _, b := range bindings {
+			owners[b] = append(owners[b], action)
+		}
+	}
+	for b, actions := range owners {
+		if len(actions) > 1 {
+			sort.Strings(actions)
+			fmt.Printf("Warning: %s is bound to several actions: %s\n", b, strings.Join(actions, ", "))
+		}
+	}
+	return keymap
+}
+
+// sameBindings reports whether two binding lists name the same keys in
+// the same order, however they are spelled. Lists that do not parse never
+// match.
+func sameBindings(a, b string) bool {
+	pa, err := parseKeyBindings(a)
+	if err != nil {
+		return false
+	}
+	pb, err := parseKeyBindings(b)
+	return err == nil && slices.Equal(pa, pb)
+}
+
+// actionPressed reports whether the binding for action fired this tick.
+func (g *Game) actionPressed(action string) bool {
+	for _, b := range g.keys[action] {
+		if b.justPressed() {
+			return true
+		}
+	}
+	return false
+}
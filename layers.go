@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// layer is a named group of strokes. Each visible layer is rendered on
+// its own, with pixel-eraser strokes cutting through to transparency, and
+// the results are stacked bottom to top over the background and opened
+// image, so an eraser only affects the layer it was drawn on. A stroke's
+// Layer field indexes g.layers.
+type layer struct {
+	Name   string
+	Hidden bool
+}
+
+func defaultLayers() []layer {
+	return []layer{{Name: "Layer 1"}}
+}
+
+func copyLayers(src []layer) []layer {
+	return append([]layer(nil), src...)
+}
+
+func (g *Game) layerHidden(i int) bool {
+	return i >= 0 && i < len(g.layers) && g.layers[i].Hidden
+}
+
+// renderLayers draws the visible layers [from, to) onto dst, bottom to
+// top, including the in-progress stroke when live is set. Each layer is
+// rendered into a scratch image and then composited, so its pixel-eraser
+// strokes cut only its own strokes. See renderScene for the meaning of
+// the other arguments.
+func (g *Game) renderLayers(dst *ebiten.Image, xf func(Vec2) Vec2, scale float64, mask bool, from, to int, live bool) {
+	bounds := dst.Bounds()
+	buf, release := g.layerBuffer(bounds.Size())
+	defer release()
+	// The scratch image starts at the origin, wherever dst starts.
+	dx, dy := float32(bounds.Min.X), float32(bounds.Min.Y)
+	local := func(p Vec2) Vec2 {
+		q := xf(p)
+		return Vec2{X: q.X - dx, Y: q.Y - dy}
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(dx), float64(dy))
+	for l := from; l < to; l++ {
+		strokes := g.layerStrokes(l, live)
+		if len(strokes) == 0 {
+			continue
+		}
+		buf.Clear()
+		batch := &strokeBatch{dst: buf, mask: mask, smooth: g.smoothStrokes}
+		for _, s := range strokes {
+			batch.add(s, local, scale)
+		}
+		batch.flush()
+		dst.DrawImage(buf, op)
+	}
+}
+
+// layerStrokes returns the visible strokes of layer l in paint order,
+// with the in-progress stroke when live is set.
+func (g *Game) layerStrokes(l int, live bool) []*stroke {
+	if g.layerHidden(l) {
+		return nil
+	}
+	var out []*stroke
+	for _, s := range g.strokes {
+		if s.Layer == l && g.strokeVisible(s) {
+			out = append(out, s)
+		}
+	}
+	if live && g.current.Layer == l {
+		out = append(out, g.current)
+	}
+	if g.newestBelow {
+		out = newestBelowOrder(out)
+	}
+	return out
+}
+
+// newestBelowOrder reverses the ink in strokes, so newer strokes sit under
+// older ones, and paints pixel-eraser strokes after all of it. An eraser
+// reversed along with the ink would be painted before the strokes it was
+// meant to erase.
+func newestBelowOrder(strokes []*stroke) []*stroke {
+	out := make([]*stroke, 0, len(strokes))
+	for i := len(strokes) - 1; i >= 0; i-- {
+		if !strokes[i].Eraser {
+			out = append(out, strokes[i])
+		}
+	}
+	for _, s := range strokes {
+		if s.Eraser {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// layerBuffer returns a cleared image of the given size, anchored at the
+// origin, to render one layer into. Sizes that fit the canvas share one
+// image kept between calls; larger ones, such as enlarged exports, get
+// their own, which release disposes.
+func (g *Game) layerBuffer(size image.Point) (buf *ebiten.Image, release func()) {
+	cs := g.canvas.Bounds().Size()
+	if size.X > cs.X || size.Y > cs.Y {
+		buf = ebiten.NewImage(size.X, size.Y)
+		return buf, buf.Dispose
+	}
+	if g.layerBuf == nil || g.layerBuf.Bounds().Size() != cs {
+		if g.layerBuf != nil {
+			g.layerBuf.Dispose()
+		}
+		g.layerBuf = ebiten.NewImage(cs.X, cs.Y)
+	}
+	return g.layerBuf.SubImage(image.Rectangle{Max: size}).(*ebiten.Image), func() {}
+}
+
+// strokesByLayer returns the strokes ordered bottom layer first, keeping
+// drawing order within each layer.
+func (g *Game) strokesByLayer() []*stroke {
+	out := make([]*stroke, 0, len(g.strokes))
+	for l := range g.layers {
+		for _, s := range g.strokes {
+			if s.Layer == l {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// addLayer inserts a new layer above the active one and makes it active.
+func (g *Game) addLayer() {
+	at := g.activeLayer + 1
+	for _, s := range g.strokes {
+		if s.Layer >= at {
+			s.Layer++
+		}
+	}
+	g.layers = append(g.layers[:at], append([]layer{{Name: g.nextLayerName()}}, g.layers[at:]...)...)
+	g.activeLayer = at
+	g.recordState()
+}
+
+// deleteLayer removes the active layer and its strokes. The last layer
+// cannot be deleted.
+func (g *Game) deleteLayer() {
+	if len(g.layers) <= 1 {
+		fmt.Println("Cannot delete the only layer")
+		return
+	}
+	at := g.activeLayer
+	kept := g.strokes[:0]
+	for _, s := range g.strokes {
+		switch {
+		case s.Layer == at:
+			continue
+		case s.Layer > at:
+			s.Layer--
+		}
+		kept = append(kept, s)
+	}
+	g.strokes = kept
+	g.index.invalidate()
+	g.layers = append(g.layers[:at], g.layers[at+1:]...)
+	g.activeLayer = max(0, at-1)
+	g.rebuildCanvas()
+	g.recordState()
+}
+
+func (g *Game) cycleLayer() {
+	g.activeLayer = (g.activeLayer + 1) % len(g.layers)
+}
+
+func (g *Game) toggleLayerVisibility() {
+	g.layers[g.activeLayer].Hidden = !g.layers[g.activeLayer].Hidden
+	g.rebuildCanvas()
+	g.recordState()
+}
+
+func (g *Game) nextLayerName() string {
+	taken := map[string]bool{}
+	for _, l := range g.layers {
+		taken[l.Name] = true
+	}
+	for n := len(g.layers) + 1; ; n++ {
+		name := fmt.Sprintf("Layer %d", n)
+		if !taken[name] {
+			return name
+		}
+	}
+}
+
+func (g *Game) layerLabel() string {
+	l := g.layers[g.activeLayer]
+	label := fmt.Sprintf("%s (%d/%d)", l.Name, g.activeLayer+1, len(g.layers))
+	if l.Hidden {
+		label += " hidden"
+	}
+	return label
+}
+
+func (g *Game) layerCommands() []command {
+	return []command{
+		{name: "New Layer", run: g.addLayer},
+		{name: "Delete Layer", run: g.deleteLayer},
+		{name: "Next Layer", run: g.cycleLayer},
+		{name: "Layer Visibility: " + onOff(!g.layers[g.activeLayer].Hidden), run: g.toggleLayerVisibility},
+	}
+}
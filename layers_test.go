@@ -0,0 +1,42 @@
+package main
+
+import (
+	"image/color"
+	"slices"
+	"testing"
+)
+
+// TestLayerStrokesOrder checks paint order within a layer. With Newest
+// Below on, an eraser must still be painted after the older ink it
+// covers, or erasing would do nothing.
+func TestLayerStrokesOrder(t *testing.T) {
+	ink := zigzag(0, 0, 10, color.White)
+	eraser := zigzag(0, 0, 10, color.White)
+	eraser.Eraser = true
+	newer := zigzag(0, 4, 10, color.Black)
+	other := zigzag(0, 8, 10, color.Black)
+	other.Layer = 1
+	live := zigzag(0, 2, 10, color.White)
+	live.Eraser = true
+
+	for _, tc := range []struct {
+		name        string
+		newestBelow bool
+		current     *stroke
+		want        []*stroke
+	}{
+		{"drawing order", false, nil, []*stroke{ink, eraser, newer}},
+		{"newest below", true, nil, []*stroke{newer, ink, eraser}},
+		{"newest below, live eraser", true, live, []*stroke{newer, ink, eraser, live}},
+	} {
+		g := &Game{
+			layers:      []layer{{Name: "Layer 1"}, {Name: "Layer 2"}},
+			strokes:     []*stroke{ink, eraser, other, newer},
+			newestBelow: tc.newestBelow,
+			current:     tc.current,
+		}
+		if got := g.layerStrokes(0, tc.current != nil); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// liveStroke holds the layers used while drawing a stroke that cannot be
+// stamped straight onto the canvas: a translucent one, which must be
+// composited as a whole, a pixel eraser, which cuts only through its own
+// layer, or any stroke with "Newest Below" on. New segments are painted
+// opaque onto layer (or cut out of it, for an eraser) and each frame only
+// re-blends the three images, so the cost per frame does not grow with
+// the stroke's length. The full re-render is deferred until the stroke is
+// committed.
+type liveStroke struct {
+	below *ebiten.Image // background and everything under the stroke
+	layer *ebiten.Image // the stroke, or for an eraser its layer's strokes
+	above *ebiten.Image // everything drawn over the stroke
+}
+
+func (l *liveStroke) dispose() {
+	l.below.Dispose()
+	l.layer.Dispose()
+	l.above.Dispose()
+}
+
+// needsLiveLayer reports whether the stroke being drawn is composited
+// through a liveStroke rather than stamped onto the canvas.
+func (g *Game) needsLiveLayer() bool {
+	return g.current != nil && (g.newestBelow || g.current.alpha() < 1 || g.current.Eraser)
+}
+
+// liveColor is the color the live stroke is painted with. Only the
+// coverage of an eraser matters, since cutSegment removes it from the
+// layer.
+func (g *Game) liveColor() color.Color {
+	if g.current.Eraser {
+		return color.White
+	}
+	return g.current.Color
+}
+
+// cutSegment erases the segment from a to b, in canvas coordinates, out
+// of the live eraser's layer. A zero-length segment cuts a single dot.
+func (g *Game) cutSegment(a, b Vec2, width float32) {
+	batch := &strokeBatch{dst: g.strokeTarget(), clr: color.White, width: width, cut: true}
+	switch {
+	case g.current.Square:
+		forSquareStamps(a, b, width, batch.appendSquare)
+	case a == b:
+		batch.appendSegment(Vec2{X: a.X - 0.25, Y: a.Y}, Vec2{X: b.X + 0.25, Y: b.Y}, width)
+	default:
+		batch.appendSegment(a, b, width)
+	}
+	batch.flush()
+}
+
+// strokeTarget returns the image the live stroke's segments are painted
+// onto, (re)building the live layers if the canvas changed size.
+func (g *Game) strokeTarget() *ebiten.Image {
+	if !g.needsLiveLayer() {
+		return g.canvas
+	}
+	if g.live == nil || g.live.layer.Bounds() != g.canvas.Bounds() {
+		g.beginLive()
+	}
+	return g.live.layer
+}
+
+// beginLive renders the scene around the live stroke into separate
+// layers, along with whatever part of the stroke already exists. The
+// layers are reused while the canvas keeps its size, so a canvas sliding
+// at its maximum size does not reallocate them.
+func (g *Game) beginLive() {
+	w, h := g.canvas.Bounds().Dx(), g.canvas.Bounds().Dy()
+	l := g.live
+	if l == nil || l.layer.Bounds() != g.canvas.Bounds() {
+		if l != nil {
+			l.dispose()
+		}
+		l = &liveStroke{below: ebiten.NewImage(w, h), layer: ebiten.NewImage(w, h), above: ebiten.NewImage(w, h)}
+	} else {
+		l.layer.Clear()
+		l.above.Clear()
+	}
+	l.below.Fill(g.bgColor)
+	g.drawBaseImage(l.below, g.worldToCanvas, 1)
+	at := g.current.Layer
+	if g.current.Eraser {
+		// The eraser's own layer, with the stroke so far cut out of it,
+		// goes between the layers under and over it.
+		g.renderLayers(l.below, g.worldToCanvas, 1, false, 0, at, false)
+		g.renderLayers(l.layer, g.worldToCanvas, 1, false, at, at+1, true)
+		g.renderLayers(l.above, g.worldToCanvas, 1, false, at+1, len(g.layers), false)
+		g.renderTextBoxes(l.above, g.worldToCanvas, 1)
+		g.live = l
+		return
+	}
+	// With Newest Below the stroke sits under its own layer's strokes.
+	split := at + 1
+	if g.newestBelow {
+		split = at
+	}
+	g.renderLayers(l.below, g.worldToCanvas, 1, false, 0, split, false)
+	g.renderLayers(l.above, g.worldToCanvas, 1, false, split, len(g.layers), false)
+	g.renderTextBoxes(l.above, g.worldToCanvas, 1)
+
+	batch := &strokeBatch{dst: l.layer, smooth: g.smoothStrokes}
+	s := *g.current
+	s.Color = g.liveColor()
+	batch.addOpaque(&s, g.worldToCanvas, 1)
+	batch.flush()
+	g.live = l
+}
+
+// compositeLive redraws the canvas from the live layers.
+func (g *Game) compositeLive() {
+	l := g.live
+	g.canvas.Clear()
+	g.canvas.DrawImage(l.below, nil)
+	op := &ebiten.DrawImageOptions{}
+	if !g.current.Eraser {
+		op.ColorScale.ScaleAlpha(float32(g.current.alpha()))
+	}
+	g.canvas.DrawImage(l.layer, op)
+	g.canvas.DrawImage(l.above, nil)
+}
+
+// endLive drops the live layers and repaints the canvas in full, which
+// also replaces the per-segment approximation with the batched render.
+func (g *Game) endLive() {
+	if g.live == nil {
+		return
+	}
+	g.live.dispose()
+	g.live = nil
+	g.rebuildCanvas()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+// BenchmarkLiveStroke compares the per-frame triangle build for a
+// translucent 5,000-point stroke before and after live layers. "whole"
+// rebuilds the entire stroke, as every frame did when the scene was
+// re-rendered per point; "segment" builds only the newest segment, which
+// is all a frame paints onto the live layer now. Blending the three
+// layers needs a graphics context and is not measured.
+func BenchmarkLiveStroke(b *testing.B) {
+	s := zigzag(0, 0, 5000, color.RGBA{40, 40, 200, 128})
+	n := len(s.Points)
+	last := &stroke{Points: s.Points[n-2:], Size: s.Size, Color: s.Color}
+	last.recomputeBounds()
+	for _, bc := range []struct {
+		name string
+		s    *stroke
+	}{
+		{"whole", s},
+		{"segment", last},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				batch := &strokeBatch{}
+				batch.addOpaque(bc.s, identity, 1)
+				batch.flush()
+			}
+		})
+	}
+}
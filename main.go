@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
+	"image/draw"
+	"image/jpeg"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -28,11 +31,20 @@ const (
 	modePixelErase
 	modeStrokeErase
 	modeText
+	modeArtboard
+	modeEyedropper
+	modeFill
 )
 
 const (
 	initialCanvasSize = 2048
-	uiHeight          = 110
+	// maxCanvasSide caps how far the infinite canvas's backing image
+	// grows. Past it the image slides to follow the brush instead. Up to
+	// five canvas-sized images exist at once (the canvas, renderLayers'
+	// scratch image, and a live stroke's three layers), so at the cap they
+	// take 5 × 6144² × 4 bytes, about 720 MiB of GPU memory.
+	maxCanvasSide = 6144
+	uiHeight      = 150
 )
 
 var uiFont font.Face
@@ -55,6 +67,27 @@ type stroke struct {
 	Color  color.Color
 	Bounds image.Rectangle
 	Erased bool
+	// Tags group strokes for filtering; see strokeTagPresets.
+	Tags []string
+	// Eraser marks pixel-eraser strokes, which cut through their layer.
+	Eraser bool
+	// Square marks pixel-eraser strokes stamped as axis-aligned squares
+	// rather than a round-capped line.
+	Square bool
+	// Layer indexes Game.layers.
+	Layer int
+	// Opacity applies to the stroke as a whole, on top of Color's alpha.
+	// Zero means fully opaque so older stroke values render unchanged.
+	Opacity float64
+	// Widths, when set, holds a width per point for strokes tapered by
+	// cursor speed. Size is then the widest the stroke can get.
+	Widths []float32
+	// Flow is the share of paint each stamp lays down, so low-flow
+	// strokes build up where they overlap. Zero means full flow.
+	Flow float64
+	// Fill, when set, makes this a paint-bucket fill painted through the
+	// region's mask. Points then holds only the clicked point.
+	Fill *fillRegion
 }
 
 type textBox struct {
@@ -65,9 +98,54 @@ type textBox struct {
 
 func (s *stroke) expandBounds(p Vec2) {
 	if len(s.Points) == 1 {
-		s.Bounds = image.Rect(int(p.X), int(p.Y), int(p.X), int(p.Y))
+		s.Bounds = pointRect(p)
 	} else {
-		s.Bounds = s.Bounds.Union(image.Rect(int(p.X), int(p.Y), int(p.X), int(p.Y)))
+		s.Bounds = s.Bounds.Union(pointRect(p))
+	}
+}
+
+// pointRect is the one-pixel rectangle containing p. Bounds are built from
+// these rather than zero-size rectangles, which Union treats as empty and
+// drops.
+func pointRect(p Vec2) image.Rectangle {
+	x, y := int(math.Floor(float64(p.X))), int(math.Floor(float64(p.Y)))
+	return image.Rect(x, y, x+1, y+1)
+}
+
+// paintBounds is the world rectangle s can paint: its points grown by
+// half its width plus room for antialiasing and, when smooth, for curves
+// that bulge past their points.
+func (s *stroke) paintBounds(smooth bool) image.Rectangle {
+	pad := s.Size/2 + 2
+	if smooth && !s.Square {
+		var longest float64
+		for i := 1; i < len(s.Points); i++ {
+			longest = math.Max(longest, math.Sqrt(sqDist(s.Points[i-1], s.Points[i])))
+		}
+		pad += longest / 2
+	}
+	return s.Bounds.Inset(-int(math.Ceil(pad)))
+}
+
+func (s *stroke) alpha() float64 {
+	if s.Opacity <= 0 || s.Opacity > 1 {
+		return 1
+	}
+	return s.Opacity
+}
+
+func (s *stroke) recomputeBounds() {
+	if s.Fill != nil {
+		s.Bounds = s.Fill.Mask.Rect
+		return
+	}
+	for i, p := range s.Points {
+		r := pointRect(p)
+		if i == 0 {
+			s.Bounds = r
+		} else {
+			s.Bounds = s.Bounds.Union(r)
+		}
 	}
 }
 
@@ -75,6 +153,9 @@ func (s *stroke) hit(pos Vec2, radius float64) bool {
 	if s.Erased || len(s.Points) == 0 {
 		return false
 	}
+	if s.Fill != nil {
+		return s.Fill.covers(pos)
+	}
 
 	hitRadius := radius + s.Size/2
 	if len(s.Points) == 1 {
@@ -126,6 +207,19 @@ func drawText(dst *ebiten.Image, str string, x, y int, clr color.Color) {
 	text.Draw(dst, str, uiFont, x, y, clr)
 }
 
+// fitText cuts s short with "..." so it is at most width pixels wide in
+// the UI font.
+func fitText(s string, width int) string {
+	if uiFont == nil || font.MeasureString(uiFont, s).Round() <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && font.MeasureString(uiFont, string(runes)+"...").Round() > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
 func sizedFont(size float64) font.Face {
 	if face, ok := faceCache[size]; ok {
 		return face
@@ -168,10 +262,19 @@ func (s *slider) handleInput(mx, my float64, pressed bool) {
 func (s *slider) draw(dst *ebiten.Image, label string) {
 	barY := s.y
 	trackHeight := 6.0
-	vector.DrawFilledRect(dst, float32(s.x), float32(barY-trackHeight/2), float32(s.width), float32(trackHeight), color.RGBA{60, 60, 60, 255}, false)
+	trackColor := color.RGBA{60, 60, 60, 255}
+	knobColor := color.RGBA{200, 200, 200, 255}
+	if s.active {
+		trackColor = color.RGBA{70, 100, 140, 255}
+		knobColor = color.RGBA{120, 180, 240, 255}
+	}
+	vector.DrawFilledRect(dst, float32(s.x), float32(barY-trackHeight/2), float32(s.width), float32(trackHeight), trackColor, false)
 	knobRadius := 10.0
 	knobX := s.x + ((*s.value - s.min) / (s.max - s.min) * s.width)
-	vector.DrawFilledCircle(dst, float32(knobX), float32(barY), float32(knobRadius), color.RGBA{200, 200, 200, 255}, false)
+	if s.active {
+		vector.StrokeCircle(dst, float32(knobX), float32(barY), float32(knobRadius+3), 2, knobColor, true)
+	}
+	vector.DrawFilledCircle(dst, float32(knobX), float32(barY), float32(knobRadius), knobColor, false)
 	drawText(dst, fmt.Sprintf("%s: %.1f", label, *s.value), int(s.x), int(s.y)-8, color.White)
 }
 
@@ -232,10 +335,38 @@ type fileEntry struct {
 }
 
 type saveDialog struct {
-	visible   bool
-	directory string
-	filename  string
-	entries   []fileEntry
+	visible     bool
+	directory   string
+	filename    string
+	entries     []fileEntry
+	selected    int
+	listFocus   bool
+	sizes       int
+	lastClick   int
+	srgb        bool
+	mask        bool
+	transparent bool
+	// quality is the JPEG quality, adjusted with qualitySlider when the
+	// filename has a JPEG extension.
+	quality       float64
+	qualitySlider slider
+	// opening switches the dialog from saving to picking a file to open.
+	opening bool
+	// caret is the rune index in filename where typing goes.
+	caret int
+	// withPNG also writes a flattened PNG next to a saved project.
+	withPNG bool
+	// failure is the error from the last save attempt, shown until the
+	// next one.
+	failure string
+	// writable caches whether checkedDir, the folder the typed path saves
+	// into, can be written; see targetWritable.
+	writable   bool
+	checkedDir string
+	// scroll is the index of the first entry shown in the file list, and
+	// wheel holds scrolling not yet worth a whole row.
+	scroll int
+	wheel  float64
 }
 
 func (s *saveDialog) loadEntries() {
@@ -261,6 +392,53 @@ func (s *saveDialog) loadEntries() {
 	}
 
 	s.entries = entries
+	// Check again on entering a folder, in case its permissions changed.
+	s.checkedDir = ""
+	s.selected = -1
+	s.scroll = 0
+	s.listFocus = false
+}
+
+// openEntry descends into a directory entry or copies a file entry's name
+// into the filename field.
+func (s *saveDialog) openEntry(idx int) {
+	if idx < 0 || idx >= len(s.entries) {
+		return
+	}
+	entry := s.entries[idx]
+	if !entry.dir {
+		s.setFilename(entry.name)
+		s.listFocus = false
+		return
+	}
+	next := filepath.Join(s.directory, entry.name)
+	if entry.name == ".." {
+		next = filepath.Dir(s.directory)
+	}
+	if info, err := os.Stat(next); err == nil && info.IsDir() {
+		s.directory = next
+		s.loadEntries()
+		s.listFocus = true
+		if len(s.entries) > 0 {
+			s.selected = 0
+		}
+	}
+}
+
+// moveSelection steps the highlighted list entry by delta and gives the
+// list keyboard focus.
+func (s *saveDialog) moveSelection(delta int) {
+	if len(s.entries) == 0 {
+		return
+	}
+	s.listFocus = true
+	s.selected += delta
+	if s.selected < 0 {
+		s.selected = 0
+	}
+	if s.selected >= len(s.entries) {
+		s.selected = len(s.entries) - 1
+	}
 }
 
 func (c *confirmDialog) draw(dst *ebiten.Image) {
@@ -305,23 +483,80 @@ func (c *confirmDialog) handleInput(mx, my, viewW, viewH int, pressed bool) {
 }
 
 type Game struct {
-	canvas        *ebiten.Image
-	canvasOrigin  vec2d
-	strokes       []*stroke
-	current       *stroke
-	currentMode   toolMode
-	mode          toolMode
-	brushSize     float64
-	eraserSize    float64
-	textSize      float64
+	canvas       *ebiten.Image
+	canvasOrigin vec2d
+	strokes      []*stroke
+	current      *stroke
+	currentMode  toolMode
+	mode         toolMode
+	brushSize    float64
+	eraserSize   float64
+	textSize     float64
+	brushOpacity float64
+	brushFlow    float64
+	// minSpacing is how far, in world pixels, the cursor must move before
+	// a new point is captured.
+	minSpacing    float64
 	textBoxes     []textBox
 	buttons       []*button
 	sliders       []*slider
 	confirm       confirmDialog
 	save          saveDialog
+	palette       commandPalette
+	task          *backgroundTask
+	newDoc        newDialog
+	pageSize      pageSizeDialog
+	fixedSize     image.Point
+	artboards     []artboard
+	boardDrag     *image.Rectangle
+	pixelSnap     bool
+	showStats     bool
+	frame         int
+	tools         map[toolMode]Tool
+	keys          map[string][]keyBinding
+	picker        colorPicker
+	activeTag     string
+	bgColor       color.Color
+	baseImage     *ebiten.Image
+	hiddenTags    map[string]bool
+	historyLimit  int
+	clickDebounce int
+	vsync         bool
+	fpsCap        int
+	lastDraw      time.Time
+	sceneDirty    bool
+	lastCursor    image.Point
+	lastScreen    image.Point
+	toolHeld      bool
 	lastMouseBtn  bool
 	camera        vec2d
+	zoom          float64
 	panning       bool
+	spacePan      bool
+	squareEraser  bool
+	smoothStrokes bool
+	velocityWidth bool
+	splitStrokes  bool
+	hideUI        bool
+	snapToGrid    bool
+	screenSizes   bool
+	showGrid      bool
+	gridSize      float64
+	live          *liveStroke
+	// layerBuf is the scratch image renderLayers renders each layer into.
+	layerBuf *ebiten.Image
+	// notice is a message shown in the bottom strip until noticeUntil.
+	notice      string
+	noticeUntil time.Time
+	// stamps spaces the live stroke's flow stamps across segments.
+	stamps        stamper
+	index         strokeIndex
+	layers        []layer
+	checker       *checkerboard
+	scrollMode    string
+	activeLayer   int
+	aspectRatios  []aspectRatio
+	ratioIndex    int
 	panLast       Vec2
 	panRelease    time.Time
 	ignoreInput   bool
@@ -332,53 +567,173 @@ type Game struct {
 	undoStack     []drawingState
 	redoStack     []drawingState
 	textSizeDirty bool
+	brushColor    color.Color
+	secondColor   color.Color
+	eraseOnlyMine bool
+	newestBelow   bool
+	orderButton   *button
+	resizing      *slider
+	resizeAnchor  Vec2
+	resizeStart   float64
 }
 
 type drawingState struct {
 	strokes      []*stroke
 	textBoxes    []textBox
+	artboards    []artboard
+	layers       []layer
+	fixedSize    image.Point
 	canvasOrigin vec2d
 	camera       vec2d
+	// baseImage is shared between snapshots; it is never modified.
+	baseImage *ebiten.Image
 }
 
 func NewGame() *Game {
 	initFont()
+	cfg := loadConfig()
 	g := &Game{
 		canvas:       ebiten.NewImage(initialCanvasSize, initialCanvasSize),
 		canvasOrigin: vec2d{X: -initialCanvasSize / 2, Y: -initialCanvasSize / 2},
 		strokes:      []*stroke{},
+		layers:       defaultLayers(),
 		mode:         modeDraw,
 		currentMode:  modeDraw,
 		brushSize:    10,
 		eraserSize:   20,
 		textSize:     24,
+		brushOpacity: 1,
+		brushFlow:    1,
+		gridSize:     32,
 		textBoxes:    []textBox{},
 		selectedText: -1,
 		editingText:  -1,
-	}
-	g.canvas.Fill(color.Black)
+		brushColor:   color.White,
+		bgColor:      color.Black,
+		zoom:         1,
+		secondColor:  color.RGBA{128, 128, 128, 255},
+		tools:        defaultTools(),
+		keys:         buildKeymap(cfg.Keys),
+		historyLimit: cfg.HistoryLimit,
+		aspectRatios: buildAspectRatios(cfg.ArtboardRatios),
+		checker:      newCheckerboard(cfg.Checker),
+		scrollMode:   parseScrollMode(cfg.Scroll),
+		vsync:        true,
+		sceneDirty:   true,
+	}
+	g.applyToolSettings(cfg.Tools)
+	// Update runs at Ebiten's default rate, so the debounce counts ticks.
+	g.clickDebounce = max(0, cfg.ClickDebounce) * ebiten.DefaultTPS / 1000
+	g.canvas.Fill(g.bgColor)
 	g.setupUI()
+	g.clampSliders()
 	g.recordState()
 	return g
 }
 
 func (g *Game) setupUI() {
 	btns := []*button{
-		{rect: image.Rect(20, 20, 120, 60), label: "Brush", onClick: func() { g.mode = modeDraw }},
-		{rect: image.Rect(140, 20, 260, 60), label: "Pixel Eraser", onClick: func() { g.mode = modePixelErase }},
-		{rect: image.Rect(260, 20, 380, 60), label: "Stroke Eraser", onClick: func() { g.mode = modeStrokeErase }},
-		{rect: image.Rect(380, 20, 500, 60), label: "Text", onClick: func() { g.mode = modeText }},
-		{rect: image.Rect(520, 20, 640, 60), label: "Save", onClick: func() { g.saveImage() }},
-		{rect: image.Rect(660, 20, 780, 60), label: "Clear", onClick: func() { g.confirmClear() }},
+		{rect: image.Rect(20, 20, 120, 60), label: "Brush", onClick: func() { g.setMode(modeDraw) }},
+		{rect: image.Rect(140, 20, 260, 60), label: "Pixel Eraser", onClick: func() { g.setMode(modePixelErase) }},
+		{rect: image.Rect(260, 20, 380, 60), label: "Stroke Eraser", onClick: func() { g.setMode(modeStrokeErase) }},
+		{rect: image.Rect(380, 20, 500, 60), label: "Text", onClick: func() { g.setMode(modeText) }},
+		{rect: image.Rect(520, 20, 585, 60), label: "Save", onClick: func() { g.saveImage() }},
+		{rect: image.Rect(592, 20, 657, 60), label: "Clear", onClick: func() { g.confirmClear() }},
+		{rect: image.Rect(664, 20, 724, 60), label: "Pick", onClick: func() { g.setMode(modeEyedropper) }},
+		{rect: image.Rect(731, 20, 791, 60), label: "Fill", onClick: func() { g.setMode(modeFill) }},
+		{rect: image.Rect(960, 112, 1060, 138), label: "Open", onClick: func() { g.openFile() }},
+		{rect: image.Rect(1080, 112, 1160, 138), label: "Fit", onClick: func() { g.fitView() }},
+	}
+	eraserShape := &button{rect: image.Rect(1170, 112, 1270, 138), label: g.eraserShapeLabel()}
+	eraserShape.onClick = func() {
+		g.squareEraser = !g.squareEraser
+		eraserShape.label = g.eraserShapeLabel()
+	}
+	btns = append(btns, eraserShape)
+	colorFilter := &button{rect: image.Rect(20, 72, 220, 98), label: "Erase Color Only: Off"}
+	colorFilter.onClick = func() {
+		g.eraseOnlyMine = !g.eraseOnlyMine
+		colorFilter.label = "Erase Color Only: " + onOff(g.eraseOnlyMine)
+	}
+	g.orderButton = &button{rect: image.Rect(240, 72, 420, 98), label: "Newest Below: " + onOff(g.newestBelow)}
+	g.orderButton.onClick = func() {
+		g.setNewestBelow(!g.newestBelow)
+		g.rebuildCanvas()
 	}
+	newButton := &button{rect: image.Rect(440, 72, 520, 98), label: "New", onClick: func() { g.newDoc.visible = true }}
+	boardTool := &button{rect: image.Rect(540, 72, 640, 98), label: "Artboard", onClick: func() { g.setMode(modeArtboard) }}
+	boardExport := &button{rect: image.Rect(660, 72, 800, 98), label: "Export Boards", onClick: func() { g.exportArtboards() }}
+	colorButton := &button{rect: image.Rect(820, 72, 900, 98), label: "Color", onClick: func() { g.toggleColorPicker() }}
+	tagButton := &button{rect: image.Rect(920, 72, 1060, 98), label: g.activeTagLabel()}
+	tagButton.onClick = func() {
+		g.cycleActiveTag()
+		tagButton.label = g.activeTagLabel()
+	}
+	bgButton := &button{rect: image.Rect(1080, 72, 1200, 98), label: "Background", onClick: func() { g.cycleBackground() }}
+	btns = append(btns, colorFilter, g.orderButton, newButton, boardTool, boardExport, colorButton, tagButton, bgButton)
 	g.buttons = btns
 	g.sliders = []*slider{
 		{x: 820, y: 40, width: 160, min: 2, max: 60, value: &g.brushSize},
 		{x: 1000, y: 40, width: 160, min: 4, max: 80, value: &g.eraserSize},
 		{x: 1180, y: 40, width: 160, min: 10, max: 80, value: &g.textSize},
+		{x: 300, y: 130, width: 90, min: 0.05, max: 1, value: &g.brushOpacity},
+		{x: 520, y: 130, width: 160, min: 0, max: 40, value: &g.minSpacing},
+		{x: 700, y: 130, width: 80, min: 4, max: 128, value: &g.gridSize},
+		{x: 420, y: 130, width: 80, min: 0.05, max: 1, value: &g.brushFlow},
 	}
 }
 
+// setNewestBelow sets the paint order, which is saved with the document,
+// and its button label. Callers redraw the canvas.
+func (g *Game) setNewestBelow(on bool) {
+	g.newestBelow = on
+	if g.orderButton != nil {
+		g.orderButton.label = "Newest Below: " + onOff(on)
+	}
+}
+
+func (g *Game) eraserShapeLabel() string {
+	if g.squareEraser {
+		return "Eraser: Square"
+	}
+	return "Eraser: Round"
+}
+
+func onOff(v bool) string {
+	if v {
+		return "On"
+	}
+	return "Off"
+}
+
+// backgroundPresets are the canvas colors the Background button cycles.
+var backgroundPresets = []color.Color{
+	color.Black,
+	color.White,
+	color.RGBA{245, 240, 225, 255},
+	color.RGBA{48, 48, 48, 255},
+	color.Transparent,
+}
+
+// cycleBackground switches to the next background preset and repaints the
+// canvas, since erased regions show the background through.
+func (g *Game) cycleBackground() {
+	next := backgroundPresets[0]
+	for i, c := range backgroundPresets {
+		if sameColor(c, g.bgColor) && i+1 < len(backgroundPresets) {
+			next = backgroundPresets[i+1]
+		}
+	}
+	g.bgColor = next
+	g.rebuildCanvas()
+}
+
+func sameColor(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
+
 func (g *Game) canvasRect() image.Rectangle {
 	originX := int(math.Floor(g.canvasOrigin.X))
 	originY := int(math.Floor(g.canvasOrigin.Y))
@@ -386,6 +741,9 @@ func (g *Game) canvasRect() image.Rectangle {
 }
 
 func (g *Game) ensurePointVisible(p Vec2, radius float64) {
+	if g.fixedCanvas() {
+		return
+	}
 	margin := int(math.Ceil(radius)) + 8
 	neededMinX := int(math.Floor(float64(p.X))) - margin
 	neededMaxX := int(math.Ceil(float64(p.X))) + margin
@@ -421,20 +779,132 @@ func (g *Game) ensurePointVisible(p Vec2, radius float64) {
 		newH += extra
 		expanded = true
 	}
+	if !expanded {
+		return
+	}
 
-	if expanded {
-		g.canvasOrigin = vec2d{X: float64(newOriginX), Y: float64(newOriginY)}
+	// Rather than grow past the cap, drop the side away from the point.
+	// Strokes there stay in the drawing but are off the canvas until the
+	// brush comes back near them.
+	if newW > maxCanvasSide {
+		if newOriginX == rect.Min.X {
+			newOriginX += newW - maxCanvasSide
+		}
+		newW = maxCanvasSide
+	}
+	if newH > maxCanvasSide {
+		if newOriginY == rect.Min.Y {
+			newOriginY += newH - maxCanvasSide
+		}
+		newH = maxCanvasSide
+	}
+
+	g.canvasOrigin = vec2d{X: float64(newOriginX), Y: float64(newOriginY)}
+	if newW != rect.Dx() || newH != rect.Dy() {
+		if newW == maxCanvasSide || newH == maxCanvasSide {
+			fmt.Printf("Canvas reached its maximum size of %d pixels; it now follows the brush\n", maxCanvasSide)
+		}
+		g.canvas.Dispose()
 		g.canvas = ebiten.NewImage(newW, newH)
-		g.rebuildCanvas()
+	}
+	if g.live != nil {
+		// Re-render the live layers for the new size or origin; they
+		// then make up the whole canvas.
+		g.beginLive()
+		g.compositeLive()
+		return
+	}
+	g.rebuildCanvas()
+}
+
+// canvasAtLimit reports whether the infinite canvas has stopped growing,
+// so the status strip can say why distant strokes may be missing.
+func (g *Game) canvasAtLimit() bool {
+	b := g.canvas.Bounds()
+	return !g.fixedCanvas() && (b.Dx() >= maxCanvasSide || b.Dy() >= maxCanvasSide)
+}
+
+// cameraSubpixels is the camera's fixed-point resolution. Fractional wheel
+// deltas are rounded onto this grid so every camera value is a dyadic
+// fraction that float64 holds exactly; panning out and back by the same
+// amount then lands on the same offset instead of accumulating error.
+const cameraSubpixels = 64
+
+func quantizeCamera(c vec2d) vec2d {
+	return vec2d{
+		X: math.Round(c.X*cameraSubpixels) / cameraSubpixels,
+		Y: math.Round(c.Y*cameraSubpixels) / cameraSubpixels,
 	}
 }
 
+// panBy moves the view with a cursor drag of (dx, dy) screen pixels.
+func (g *Game) panBy(dx, dy float32) {
+	g.camera.X -= float64(dx) / g.zoom
+	g.camera.Y -= float64(dy) / g.zoom
+	g.camera = quantizeCamera(g.camera)
+}
+
 func (g *Game) worldFromScreen(mx, my int) Vec2 {
-	return Vec2{X: float32(float64(mx) + g.camera.X), Y: float32(float64(my) + g.camera.Y)}
+	return Vec2{X: float32(float64(mx)/g.zoom + g.camera.X), Y: float32(float64(my)/g.zoom + g.camera.Y)}
+}
+
+// screenFromWorld maps a world position to screen pixels using the render
+// camera, for overlays drawn directly on the screen.
+func (g *Game) screenFromWorld(x, y float64) (float32, float32) {
+	cam := g.viewCamera()
+	return float32((x - cam.X) * g.zoom), float32((y - cam.Y) * g.zoom)
 }
 
+// viewCamera is the camera offset used for rendering. With pixel snapping
+// on it is rounded to whole screen pixels so the canvas blits without
+// resampling.
+func (g *Game) viewCamera() vec2d {
+	if g.pixelSnap {
+		return vec2d{X: math.Round(g.camera.X*g.zoom) / g.zoom, Y: math.Round(g.camera.Y*g.zoom) / g.zoom}
+	}
+	return g.camera
+}
+
+const (
+	minZoom  = 0.1
+	maxZoom  = 10
+	zoomStep = 1.1
+)
+
+// fitView frames the whole drawing in the viewport below the toolbar,
+// zooming out if it does not fit at 1x. With nothing drawn it centers the
+// world origin instead.
+func (g *Game) fitView() {
+	viewW, viewH := ebiten.WindowSize()
+	areaW := float64(viewW)
+	areaH := float64(viewH - g.toolbarHeight())
+	bounds, ok := g.drawingBounds()
+	if !ok {
+		g.camera = vec2d{X: -areaW / 2 / g.zoom, Y: -(float64(g.toolbarHeight()) + areaH/2) / g.zoom}
+		return
+	}
+	const margin = 40
+	zoom := math.Min((areaW-2*margin)/float64(bounds.Dx()), (areaH-2*margin)/float64(bounds.Dy()))
+	g.zoom = math.Max(minZoom, math.Min(1, zoom))
+	cx := float64(bounds.Min.X+bounds.Max.X) / 2
+	cy := float64(bounds.Min.Y+bounds.Max.Y) / 2
+	g.camera = quantizeCamera(vec2d{X: cx - areaW/2/g.zoom, Y: cy - (float64(g.toolbarHeight())+areaH/2)/g.zoom})
+}
+
+// zoomAt changes the zoom level while keeping the world point under the
+// screen position (mx, my) fixed.
+func (g *Game) zoomAt(mx, my int, zoom float64) {
+	zoom = math.Max(minZoom, math.Min(maxZoom, zoom))
+	anchor := g.worldFromScreen(mx, my)
+	g.zoom = zoom
+	g.camera.X = float64(anchor.X) - float64(mx)/zoom
+	g.camera.Y = float64(anchor.Y) - float64(my)/zoom
+}
+
+// worldToCanvas subtracts in float64 so the offset is applied exactly and
+// only the final canvas-local value is rounded to float32.
 func (g *Game) worldToCanvas(p Vec2) Vec2 {
-	return Vec2{X: p.X - float32(g.canvasOrigin.X), Y: p.Y - float32(g.canvasOrigin.Y)}
+	return Vec2{X: float32(float64(p.X) - g.canvasOrigin.X), Y: float32(float64(p.Y) - g.canvasOrigin.Y)}
 }
 
 func copyStrokes(src []*stroke) []*stroke {
@@ -444,6 +914,10 @@ func copyStrokes(src []*stroke) []*stroke {
 		clonePoints := make([]Vec2, len(s.Points))
 		copy(clonePoints, s.Points)
 		clone.Points = clonePoints
+		clone.Tags = append([]string(nil), s.Tags...)
+		if s.Widths != nil {
+			clone.Widths = append([]float32(nil), s.Widths...)
+		}
 		out[i] = &clone
 	}
 	return out
@@ -459,21 +933,42 @@ func (g *Game) captureState() drawingState {
 	return drawingState{
 		strokes:      copyStrokes(g.strokes),
 		textBoxes:    copyTextBoxes(g.textBoxes),
+		artboards:    copyArtboards(g.artboards),
+		layers:       copyLayers(g.layers),
+		fixedSize:    g.fixedSize,
 		canvasOrigin: g.canvasOrigin,
 		camera:       g.camera,
+		baseImage:    g.baseImage,
 	}
 }
 
 func (g *Game) recordState() {
 	g.undoStack = append(g.undoStack, g.captureState())
 	g.redoStack = nil
+	if g.historyLimit > 0 && len(g.undoStack) > g.historyLimit+1 {
+		// The bottom entry is the baseline undo returns to, so keep
+		// historyLimit undoable steps above it.
+		drop := len(g.undoStack) - (g.historyLimit + 1)
+		g.undoStack = append(g.undoStack[:0:0], g.undoStack[drop:]...)
+	}
 }
 
 func (g *Game) applyState(state drawingState) {
 	g.strokes = copyStrokes(state.strokes)
+	g.index.invalidate()
 	g.textBoxes = copyTextBoxes(state.textBoxes)
+	g.artboards = copyArtboards(state.artboards)
+	g.boardDrag = nil
+	g.layers = copyLayers(state.layers)
+	g.activeLayer = min(g.activeLayer, len(g.layers)-1)
+	if state.fixedSize != g.fixedSize {
+		// Undoing a page resize.
+		g.fixedSize = state.fixedSize
+		g.canvas = ebiten.NewImage(g.fixedSize.X, g.fixedSize.Y)
+	}
 	g.canvasOrigin = state.canvasOrigin
 	g.camera = state.camera
+	g.baseImage = state.baseImage
 	g.current = nil
 	g.selectedText = -1
 	g.editingText = -1
@@ -507,11 +1002,43 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 }
 
 func (g *Game) Update() error {
+	g.frame++
+	g.pollTask()
+	if g.inputActive() {
+		g.markDirty()
+	}
 	mx, my := ebiten.CursorPosition()
 	leftPressed := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
 	rightPressed := ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight)
-	rightJustPressed := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight)
-	rightJustReleased := inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonRight)
+	panPressed := ebiten.IsMouseButtonPressed(ebiten.MouseButtonMiddle)
+	panJustPressed := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonMiddle)
+	panJustReleased := inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonMiddle)
+	// In brush mode the right button paints with the secondary color, and
+	// the eyedropper and fill use it, so only the middle button pans there.
+	if g.mode != modeDraw && g.mode != modeEyedropper && g.mode != modeFill {
+		panPressed = panPressed || rightPressed
+		panJustPressed = panJustPressed || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight)
+		panJustReleased = panJustReleased || inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonRight)
+	}
+	// Holding Space turns the left button into a pan button, as in other
+	// editors. The active tool keeps its selection but gets no input.
+	typing := g.editingText >= 0 || g.save.visible || g.palette.visible || g.newDoc.visible || g.pageSize.visible
+	if !typing && ebiten.IsKeyPressed(ebiten.KeySpace) {
+		if !g.spacePan {
+			g.spacePan = true
+			g.commitCurrentStroke()
+			g.toolHeld = false
+		}
+		panPressed = panPressed || leftPressed
+		panJustPressed = panJustPressed || inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+		panJustReleased = panJustReleased || inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft)
+	} else if g.spacePan {
+		g.spacePan = false
+		if leftPressed {
+			// Don't start drawing until the drag that was panning ends.
+			g.ignoreInput = true
+		}
+	}
 	justClicked := leftPressed && !g.lastMouseBtn
 	viewW, viewH := ebiten.WindowSize()
 
@@ -535,67 +1062,114 @@ func (g *Game) Update() error {
 		return nil
 	}
 
-	return g.handleMainInput(mx, my, viewW, viewH, leftPressed, rightPressed, rightJustPressed, rightJustReleased, justClicked)
+	if g.newDoc.visible {
+		g.handleNewDialogInput(mx, my, viewW, viewH, justClicked)
+		g.lastMouseBtn = leftPressed
+		return nil
+	}
+
+	if g.pageSize.visible {
+		g.handlePageSizeInput(mx, my, viewW, viewH, justClicked)
+		g.lastMouseBtn = leftPressed
+		return nil
+	}
+
+	if g.palette.visible {
+		g.handlePaletteInput()
+		g.lastMouseBtn = leftPressed
+		return nil
+	}
+
+	return g.handleMainInput(mx, my, viewW, viewH, leftPressed, rightPressed, panPressed, panJustPressed, panJustReleased, justClicked)
 }
 
-func (g *Game) handleMainInput(mx, my, viewW, viewH int, leftPressed, rightPressed, rightJustPressed, rightJustReleased, justClicked bool) error {
+func (g *Game) handleMainInput(mx, my, viewW, viewH int, leftPressed, rightPressed, panPressed, panJustPressed, panJustReleased, justClicked bool) error {
 
-	if (ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)) && inpututil.IsKeyJustPressed(ebiten.KeyZ) {
+	if g.actionPressed(actionUndo) {
 		g.undo()
 	}
-	if (ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)) && inpututil.IsKeyJustPressed(ebiten.KeyR) {
+	if g.actionPressed(actionRedo) {
 		g.redo()
 	}
-
-	_, wheelY := ebiten.Wheel()
-	if wheelY != 0 {
-		g.camera.Y -= float64(wheelY * 60)
+	if g.actionPressed(actionPalette) {
+		g.openPalette()
+		g.lastMouseBtn = leftPressed
+		return nil
+	}
+	if g.editingText < 0 && g.actionPressed(actionSwapColors) {
+		g.brushColor, g.secondColor = g.secondColor, g.brushColor
+	}
+	if g.actionPressed(actionStats) {
+		g.showStats = !g.showStats
+	}
+	if g.editingText < 0 && g.actionPressed(actionGrid) {
+		g.toggleGrid()
 	}
+	if g.editingText < 0 && g.actionPressed(actionSnap) {
+		g.toggleSnap()
+	}
+	if g.editingText < 0 && g.actionPressed(actionHideUI) {
+		g.toggleUI()
+	}
+	if g.editingText < 0 && g.actionPressed(actionHome) {
+		g.resetView()
+	}
+	if g.editingText < 0 && g.actionPressed(actionCycleTool) {
+		g.cycleTool()
+	}
+	if g.actionPressed(actionPaste) {
+		g.pasteText(viewW, viewH)
+	}
+	if g.editingText < 0 && g.current == nil && g.actionPressed(actionStraighten) {
+		g.straightenLastStroke()
+	}
+
+	g.handleScroll(mx, my)
 	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
-		g.camera.Y -= 8
+		g.camera.Y -= 8 / g.zoom
 	}
 	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
-		g.camera.Y += 8
+		g.camera.Y += 8 / g.zoom
 	}
 
-	if rightJustPressed {
+	if panJustPressed {
 		g.panRelease = time.Time{}
 	}
 
-	if rightJustReleased && g.panning {
+	if panJustReleased && g.panning {
 		g.panRelease = time.Now().Add(150 * time.Millisecond)
 	}
 
-	effectivePan := rightPressed || (g.panning && time.Now().Before(g.panRelease))
+	effectivePan := panPressed || (g.panning && time.Now().Before(g.panRelease))
 
 	if effectivePan {
 		if !g.panning {
 			g.panning = true
 			g.panLast = Vec2{X: float32(mx), Y: float32(my)}
 		} else {
-			dy := float32(my) - g.panLast.Y
-			g.camera.Y -= float64(dy)
+			g.panBy(float32(mx)-g.panLast.X, float32(my)-g.panLast.Y)
 			g.panLast = Vec2{X: float32(mx), Y: float32(my)}
 		}
 	} else {
 		g.panning = false
 	}
 
-	g.camera.X = 0
+	g.camera = quantizeCamera(g.camera)
 
-	for _, b := range g.buttons {
-		b.updateState(mx, my, leftPressed)
-	}
-
-	for _, s := range g.sliders {
-		s.handleInput(float64(mx), float64(my), leftPressed)
+	if !g.hideUI {
+		for _, b := range g.buttons {
+			b.updateState(mx, my, leftPressed)
+		}
+		for _, s := range g.sliders {
+			s.handleInput(float64(mx), float64(my), leftPressed)
+		}
 	}
 
 	if g.editingText >= 0 {
 		g.handleTextEditing()
 	}
 
-	if justClicked && !g.panning {
+	if justClicked && !g.panning && !g.hideUI {
 		for _, b := range g.buttons {
 			if b.contains(mx, my) {
 				b.onClick()
@@ -605,7 +1179,23 @@ func (g *Game) handleMainInput(mx, my, viewW, viewH int, leftPressed, rightPress
 		}
 	}
 
-	if my <= uiHeight {
+	if g.picker.visible && g.handleColorPickerInput(mx, my, leftPressed, justClicked) {
+		g.lastMouseBtn = leftPressed
+		return nil
+	}
+
+	if g.resizing != nil {
+		g.updateBrushResize(mx, leftPressed)
+		g.lastMouseBtn = leftPressed
+		return nil
+	}
+
+	if my <= g.toolbarHeight() {
+		g.lastMouseBtn = leftPressed
+		return nil
+	}
+
+	if justClicked && !g.panning && ebiten.IsKeyPressed(ebiten.KeyAlt) && g.beginBrushResize(mx, my) {
 		g.lastMouseBtn = leftPressed
 		return nil
 	}
@@ -615,21 +1205,47 @@ func (g *Game) handleMainInput(mx, my, viewW, viewH int, leftPressed, rightPress
 		return nil
 	}
 
+	g.dispatchTool(toolInput{mx: mx, my: my, left: leftPressed, right: rightPressed, justClicked: justClicked})
+
+	g.lastMouseBtn = leftPressed
+	return nil
+}
+
+// sizeSliderForMode returns the slider that controls the active tool's
+// size, or nil when the tool has none.
+func (g *Game) sizeSliderForMode() *slider {
 	switch g.mode {
 	case modeDraw:
-		g.handleStrokeDrawing(mx, my, leftPressed, g.brushSize, color.White)
-	case modePixelErase:
-		g.handleStrokeDrawing(mx, my, leftPressed, g.eraserSize, color.Black)
-	case modeStrokeErase:
-		g.handleStrokeErase(mx, my, leftPressed)
-	case modeText:
-		g.handleTextTools(mx, my, leftPressed, justClicked)
+		return g.sliders[0]
+	case modePixelErase, modeStrokeErase:
+		return g.sliders[1]
 	}
-
-	g.lastMouseBtn = leftPressed
 	return nil
 }
 
+// beginBrushResize starts an Alt+drag resize gesture anchored at the
+// cursor. Horizontal movement then changes the active tool's size.
+func (g *Game) beginBrushResize(mx, my int) bool {
+	s := g.sizeSliderForMode()
+	if s == nil {
+		return false
+	}
+	g.resizing = s
+	g.resizeAnchor = Vec2{X: float32(mx), Y: float32(my)}
+	g.resizeStart = *s.value
+	return true
+}
+
+func (g *Game) updateBrushResize(mx int, pressed bool) {
+	if !pressed {
+		g.resizing = nil
+		return
+	}
+	s := g.resizing
+	size := g.resizeStart + (float64(mx)-float64(g.resizeAnchor.X))*0.5
+	*s.value = math.Max(s.min, math.Min(s.max, size))
+}
+
 func (g *Game) handleSaveDialogInput(mx, my, viewW, viewH int, justClicked bool) {
 	dialogW, dialogH := 720, 520
 	x := (viewW - dialogW) / 2
@@ -641,103 +1257,305 @@ func (g *Game) handleSaveDialogInput(mx, my, viewW, viewH int, justClicked bool)
 	}
 
 	cancelRect := image.Rect(x+20, y+dialogH-60, x+120, y+dialogH-20)
+	sizesRect := image.Rect(x+140, y+dialogH-60, x+300, y+dialogH-20)
+	srgbRect := image.Rect(x+20, y+dialogH-104, x+180, y+dialogH-72)
+	maskRect := image.Rect(x+200, y+dialogH-104, x+380, y+dialogH-72)
+	transparentRect := image.Rect(x+400, y+dialogH-104, x+640, y+dialogH-72)
+	withPNGRect := image.Rect(x+320, y+dialogH-60, x+500, y+dialogH-20)
 	saveRect := image.Rect(x+dialogW-180, y+dialogH-60, x+dialogW-20, y+dialogH-20)
 	nameRect := image.Rect(x+120, y+60, x+dialogW-20, y+100)
-	listRect := image.Rect(x+20, y+120, x+dialogW-20, y+dialogH-120)
-	entryHeight := 28
+	listRect := saveListRect(x, y, dialogW, dialogH)
+
+	if g.showQualitySlider() {
+		qs := g.layoutQualitySlider(x, y, dialogH)
+		qs.handleInput(float64(mx), float64(my), ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft))
+		if qs.active {
+			return
+		}
+	}
 
+	if justClicked && g.frame-g.save.lastClick < g.clickDebounce {
+		justClicked = false
+	}
 	if justClicked {
+		g.save.lastClick = g.frame
 		p := image.Pt(mx, my)
 		switch {
 		case rectContainsPoint(cancelRect, p):
 			g.save.visible = false
 			return
+		case g.save.opening && !rectContainsPoint(saveRect, p) && !rectContainsPoint(listRect, p) && !rectContainsPoint(nameRect, p):
+			// Export options are hidden while opening.
+		case rectContainsPoint(sizesRect, p):
+			g.save.sizes = g.save.sizes%3 + 1
+			return
+		case rectContainsPoint(srgbRect, p):
+			g.save.srgb = !g.save.srgb
+			return
+		case rectContainsPoint(maskRect, p):
+			g.save.mask = !g.save.mask
+			return
+		case rectContainsPoint(transparentRect, p):
+			g.save.transparent = !g.save.transparent
+			return
+		case g.showWithPNG() && rectContainsPoint(withPNGRect, p):
+			g.save.withPNG = !g.save.withPNG
+			return
 		case rectContainsPoint(saveRect, p):
-			path := filepath.Join(g.save.directory, g.save.filename)
-			if g.saveToPath(path) {
-				g.save.visible = false
-			}
+			g.confirmSaveDialog()
 			return
 		case rectContainsPoint(listRect, p):
-			idx := (my - listRect.Min.Y) / entryHeight
-			if idx >= 0 && idx < len(g.save.entries) {
-				entry := g.save.entries[idx]
-				if entry.dir {
-					next := filepath.Join(g.save.directory, entry.name)
-					if entry.name == ".." {
-						next = filepath.Dir(g.save.directory)
-					}
-					if info, err := os.Stat(next); err == nil && info.IsDir() {
-						g.save.directory = next
-						g.save.loadEntries()
-					}
-				} else {
-					g.save.filename = entry.name
-				}
+			if idx := g.save.entryAt(listRect, p); idx >= 0 {
+				g.save.selected = idx
+				g.save.openEntry(idx)
 			}
 		case rectContainsPoint(nameRect, p):
-			// keep focus on filename field
+			g.save.listFocus = false
 		}
 	}
 
+	g.save.scrollList(listRect, image.Pt(mx, my))
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		g.save.moveSelection(1)
+		g.save.revealSelected(listRows(listRect))
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		g.save.moveSelection(-1)
+		g.save.revealSelected(listRows(listRect))
+	}
+
 	chars := ebiten.AppendInputChars(nil)
-	if len(chars) > 0 {
-		g.save.filename += string(chars)
+	if g.actionPressed(actionPaste) {
+		chars = append(chars, pastedFilename()...)
+	}
+	if g.save.editFilename(chars) {
+		g.save.listFocus = false
+	}
+	if g.frame%caretBlinkTicks == 0 {
+		g.markDirty()
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(g.save.filename) > 0 {
-		g.save.filename = g.save.filename[:len(g.save.filename)-1]
+	if g.save.listFocus && inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		g.save.openEntry(g.save.selected)
+		return
 	}
 
 	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
-		path := filepath.Join(g.save.directory, g.save.filename)
-		if g.saveToPath(path) {
-			g.save.visible = false
+		g.confirmSaveDialog()
+	}
+}
+
+// showWithPNG reports whether the save dialog offers the flattened PNG
+// copy, which only applies to projects.
+func (g *Game) showWithPNG() bool {
+	return !g.save.opening && g.save.targetWritable() && isProjectPath(g.save.filename)
+}
+
+// showQualitySlider reports whether the save dialog offers JPEG quality:
+// only when saving under a JPEG name to a writable directory, since the
+// read-only note takes the same spot.
+func (g *Game) showQualitySlider() bool {
+	return !g.save.opening && g.save.targetWritable() && isJPEGPath(g.save.filename)
+}
+
+// layoutQualitySlider positions the JPEG quality slider in the dialog's
+// bottom row, keeping its drag state across frames.
+func (g *Game) layoutQualitySlider(x, y, dialogH int) *slider {
+	qs := &g.save.qualitySlider
+	qs.x, qs.y = float64(x+340), float64(y+dialogH-28)
+	qs.width, qs.min, qs.max = 160, 10, 100
+	qs.value = &g.save.quality
+	return qs
+}
+
+// confirmSaveDialog saves to, or opens, the chosen file and closes the
+// dialog on success.
+func (g *Game) confirmSaveDialog() {
+	if g.save.filename == "" || g.save.enterTypedDirectory() {
+		return
+	}
+	if !g.save.opening && !g.save.targetWritable() {
+		return
+	}
+	path := g.save.typedPath()
+	g.save.failure = ""
+	ok := false
+	if g.save.opening {
+		ok = g.openPath(path)
+	} else {
+		if filepath.Ext(path) == "" {
+			path += ".png"
+		}
+		if _, err := os.Stat(path); err == nil {
+			g.confirmOverwrite(path)
+			return
 		}
+		ok = g.saveToPath(path)
+	}
+	if ok {
+		g.save.visible = false
 	}
 }
 
-func (g *Game) handleStrokeDrawing(mx, my int, pressed bool, size float64, clr color.Color) {
+// confirmOverwrite asks before saving over an existing file, returning
+// to the save dialog if the user declines or the save fails to start.
+func (g *Game) confirmOverwrite(path string) {
+	g.save.visible = false
+	g.confirm = confirmDialog{
+		message: "Overwrite " + filepath.Base(path) + "?",
+		visible: true,
+		onConfirm: func() {
+			if !g.saveToPath(path) {
+				g.save.visible = true
+			}
+			g.ignoreInput = true
+		},
+		onCancel: func() {
+			g.save.visible = true
+			g.ignoreInput = true
+		},
+	}
+}
+
+// setMode switches tools, first committing any stroke still in progress so
+// switching mid-drag never discards it.
+func (g *Game) setMode(mode toolMode) {
+	if mode == g.mode {
+		return
+	}
+	g.commitCurrentStroke()
+	g.toolHeld = false
+	g.mode = mode
+}
+
+// commitCurrentStroke moves the in-progress stroke, if any, into history.
+func (g *Game) commitCurrentStroke() {
+	if g.current == nil || len(g.current.Points) == 0 {
+		g.current = nil
+		g.endLive()
+		return
+	}
+	g.strokes = append(g.strokes, g.current)
+	g.index.insert(g.current)
+	g.current = nil
+	g.endLive()
+	g.recordState()
+}
+
+func (g *Game) handleStrokeDrawing(mx, my int, pressed bool, size float64, clr color.Color, opacity float64) {
 	if pressed {
 		p := g.worldFromScreen(mx, my)
 		g.ensurePointVisible(p, size)
 		canvasPoint := g.worldToCanvas(p)
 		if g.current == nil || g.currentMode != g.mode {
-			g.current = &stroke{Points: []Vec2{p}, Size: size, Color: clr}
+			g.endLive()
+			if g.layerHidden(g.activeLayer) {
+				// Strokes on a hidden layer would vanish on release.
+				return
+			}
+			p = g.snapPoint(p)
+			canvasPoint = g.worldToCanvas(p)
+			g.current = &stroke{
+				Points:  []Vec2{p},
+				Size:    size,
+				Color:   clr,
+				Opacity: opacity,
+				Eraser:  g.mode == modePixelErase,
+				Square:  g.mode == modePixelErase && g.squareEraser,
+				Layer:   g.activeLayer,
+			}
+			if g.activeTag != "" {
+				g.current.Tags = []string{g.activeTag}
+			}
+			if g.velocityWidth && g.mode == modeDraw {
+				g.current.Widths = []float32{float32(size)}
+			}
+			if g.mode == modeDraw && g.brushFlow < 1 {
+				g.current.Flow = g.brushFlow
+			}
 			g.currentMode = g.mode
 			g.current.expandBounds(p)
+			clr = g.liveColor()
 		} else {
+			clr = g.liveColor()
 			last := g.current.Points[len(g.current.Points)-1]
 			dx := float64(p.X - last.X)
 			dy := float64(p.Y - last.Y)
 			distance := math.Hypot(dx, dy)
-			step := size / 4
-			if step < 0.5 {
-				step = 0.5
-			}
-			steps := int(math.Ceil(distance / step))
-			prev := last
-			if steps > 1 {
-				for i := 1; i < steps; i++ {
-					t := float64(i) / float64(steps)
-					mid := Vec2{X: last.X + float32(dx*t), Y: last.Y + float32(dy*t)}
-					g.current.Points = append(g.current.Points, mid)
-					g.current.expandBounds(mid)
-					g.drawSegment(prev, mid, size, clr)
-					prev = mid
-				}
+			if distance < g.minSpacing {
+				// Too close to the last captured point; wait for the
+				// cursor to move further.
+				return
 			}
 			g.current.Points = append(g.current.Points, p)
 			g.current.expandBounds(p)
-			g.drawSegment(prev, p, size, clr)
+			if w := g.current.Widths; w != nil {
+				g.current.Widths = append(w, velocityWidth(size, w[len(w)-1], distance*g.zoom))
+			}
+			if g.smoothStrokes && !g.current.Square {
+				// A curved segment depends on the point after it, so
+				// draw the one that just became fixed; the last one is
+				// drawn on release.
+				if n := len(g.current.Points); n >= 3 {
+					g.drawSmoothSegment(n - 3)
+				}
+			} else {
+				n := len(g.current.Points)
+				width := (g.current.widthAt(n-2) + g.current.widthAt(n-1)) / 2
+				g.drawSegment(last, p, float64(width), clr)
+			}
 		}
 		if len(g.current.Points) == 1 {
-			vector.DrawFilledCircle(g.canvas, canvasPoint.X, canvasPoint.Y, float32(size/2), clr, true)
+			dst := g.strokeTarget()
+			switch {
+			case g.current.flowRate() < 1:
+				g.drawFirstStamp()
+			case g.current.Eraser:
+				g.cutSegment(canvasPoint, canvasPoint, float32(size))
+			case g.current.Square:
+				half := float32(size / 2)
+				vector.DrawFilledRect(dst, canvasPoint.X-half, canvasPoint.Y-half, float32(size), float32(size), clr, false)
+			default:
+				vector.DrawFilledCircle(dst, canvasPoint.X, canvasPoint.Y, float32(size/2), clr, true)
+			}
+		}
+		if g.live != nil {
+			g.compositeLive()
 		}
 	} else if g.current != nil && g.currentMode == g.mode {
-		g.strokes = append(g.strokes, g.current)
-		g.current = nil
+		snapped := g.snapStrokeEnd()
+		if n := len(g.current.Points); g.smoothStrokes && !g.current.Square && n >= 2 {
+			g.drawSmoothSegment(n - 2)
+		}
+		g.commitCurrentStroke()
+		if snapped {
+			// The end was drawn where the cursor let go.
+			g.rebuildCanvas()
+		}
+	}
+}
+
+// straightenLastStroke replaces the most recent visible brush stroke with
+// the straight line between its endpoints. Pixel-eraser strokes and fills
+// are passed over.
+func (g *Game) straightenLastStroke() {
+	for i := len(g.strokes) - 1; i >= 0; i-- {
+		s := g.strokes[i]
+		if !g.strokeVisible(s) || s.Eraser || s.Fill != nil {
+			continue
+		}
+		if len(s.Points) < 3 {
+			return
+		}
+		if s.Widths != nil {
+			s.Widths = []float32{s.Widths[0], s.Widths[len(s.Widths)-1]}
+		}
+		s.Points = []Vec2{s.Points[0], s.Points[len(s.Points)-1]}
+		s.recomputeBounds()
+		g.index.invalidate()
+		g.rebuildCanvas()
 		g.recordState()
+		return
 	}
 }
 
@@ -746,16 +1564,29 @@ func (g *Game) handleStrokeErase(mx, my int, pressed bool) {
 		return
 	}
 	pos := g.worldFromScreen(mx, my)
-	tolerance := g.eraserSize / 2
-	removed := false
-	for _, s := range g.strokes {
+	if g.splitStrokes {
+		if dirty := g.splitErase(pos); !dirty.Empty() {
+			g.rebuildRegion(dirty)
+			g.recordState()
+		}
+		return
+	}
+	tolerance := g.worldSize(g.eraserSize) / 2
+	var dirty image.Rectangle
+	for s := range g.strokesNear(pos, tolerance) {
+		if !g.strokeVisible(s) {
+			continue
+		}
+		if g.eraseOnlyMine && !sameColor(s.Color, g.brushColor) {
+			continue
+		}
 		if s.hit(pos, tolerance) {
 			s.Erased = true
-			removed = true
+			dirty = dirty.Union(s.paintBounds(g.smoothStrokes))
 		}
 	}
-	if removed {
-		g.rebuildCanvas()
+	if !dirty.Empty() {
+		g.rebuildRegion(dirty)
 		g.recordState()
 	}
 }
@@ -855,7 +1686,7 @@ func (g *Game) handleTextTools(mx, my int, leftPressed, justClicked bool) {
 		}
 		g.selectedText = len(g.textBoxes)
 		g.editingText = len(g.textBoxes)
-		g.textBoxes = append(g.textBoxes, textBox{Position: pos, Text: "", Size: g.textSize})
+		g.textBoxes = append(g.textBoxes, textBox{Position: g.snapPoint(pos), Text: "", Size: g.textSize})
 		g.draggingText = false
 		g.textSizeDirty = false
 		g.rebuildCanvas()
@@ -864,7 +1695,7 @@ func (g *Game) handleTextTools(mx, my int, leftPressed, justClicked bool) {
 
 	if g.draggingText && g.selectedText >= 0 && g.selectedText < len(g.textBoxes) && leftPressed {
 		tb := &g.textBoxes[g.selectedText]
-		tb.Position = Vec2{X: pos.X - g.dragOffset.X, Y: pos.Y - g.dragOffset.Y}
+		tb.Position = g.snapPoint(Vec2{X: pos.X - g.dragOffset.X, Y: pos.Y - g.dragOffset.Y})
 		g.rebuildCanvas()
 	}
 
@@ -895,43 +1726,357 @@ func (g *Game) handleTextTools(mx, my int, leftPressed, justClicked bool) {
 }
 
 func (g *Game) rebuildCanvas() {
-	g.canvas.Fill(color.Black)
-	render := func(s *stroke) {
-		for i := 0; i < len(s.Points)-1; i++ {
-			g.drawSegment(s.Points[i], s.Points[i+1], s.Size, s.Color)
-		}
+	g.canvas.Fill(g.bgColor)
+	g.renderScene(g.canvas, g.worldToCanvas, 1, false)
+}
+
+// rebuildRegion redraws only the canvas under the world rectangle r, for
+// edits that cannot have changed anything outside it. Strokes that miss r
+// are skipped, so erasing on a large drawing costs about as much as the
+// strokes around the eraser.
+func (g *Game) rebuildRegion(r image.Rectangle) {
+	local := r.Sub(g.canvasRect().Min).Intersect(g.canvas.Bounds())
+	if local.Empty() {
+		return
 	}
+	// Drawing onto a sub-image keeps the canvas's coordinates and clips
+	// to the region.
+	sub := g.canvas.SubImage(local).(*ebiten.Image)
+	sub.Fill(g.bgColor)
+	g.renderScene(sub, g.worldToCanvas, 1, false)
+}
 
-	for _, s := range g.strokes {
-		if s.Erased {
-			continue
+// renderScene draws the opened image and every visible stroke and text
+// box over what is already on dst, which callers fill with the
+// background. xf maps world coordinates into dst's pixel space and scale
+// multiplies widths and font sizes, so the same path serves the live
+// canvas and exports. Pixel-eraser strokes cut through their own layer,
+// showing whatever is beneath it. With mask set, strokes are drawn as
+// full-coverage white to produce an alpha mask.
+func (g *Game) renderScene(dst *ebiten.Image, xf func(Vec2) Vec2, scale float64, mask bool) {
+	if !mask {
+		g.drawBaseImage(dst, xf, scale)
+	}
+	g.renderLayers(dst, xf, scale, mask, 0, len(g.layers), g.current != nil && g.currentMode == g.mode)
+	g.renderTextBoxes(dst, xf, scale)
+}
+
+func (g *Game) renderTextBoxes(dst *ebiten.Image, xf func(Vec2) Vec2, scale float64) {
+	for _, tb := range g.textBoxes {
+		renderTextBox(dst, tb, xf, scale)
+	}
+}
+
+var (
+	whiteImage    = ebiten.NewImage(3, 3)
+	whiteSubImage = whiteImage.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+)
+
+func init() {
+	whiteImage.Fill(color.White)
+}
+
+// strokeBatchChunk bounds how many points go into one path so a single
+// long stroke cannot overflow the uint16 vertex indices on its own.
+const strokeBatchChunk = 256
+
+// strokeBatch accumulates consecutive strokes that share a width and color
+// into one triangle list, so a rebuild issues a draw call per run of
+// similar strokes instead of several per segment. Paint order is kept
+// because a batch is flushed whenever the attributes change.
+type strokeBatch struct {
+	dst      *ebiten.Image
+	mask     bool
+	smooth   bool
+	width    float32
+	clr      color.Color
+	cut      bool
+	vertices []ebiten.Vertex
+	indices  []uint16
+	scratchV []ebiten.Vertex
+	scratchI []uint16
+	// draws counts the draw calls the batch has issued.
+	draws int
+}
+
+func (b *strokeBatch) add(s *stroke, xf func(Vec2) Vec2, scale float64) {
+	if len(s.Points) == 0 || !b.reaches(s, xf) {
+		return
+	}
+	if s.Fill != nil {
+		b.addFill(s, xf, scale)
+		return
+	}
+	if s.Eraser {
+		erase := *s
+		erase.Color = color.White
+		b.setCut(true)
+		b.addOpaque(&erase, xf, scale)
+		b.setCut(false)
+		return
+	}
+	if b.mask {
+		covered := *s
+		covered.Color = color.White
+		covered.Opacity = 1
+		covered.Flow = 0
+		b.addOpaque(&covered, xf, scale)
+		return
+	}
+	if s.alpha() < 1 {
+		b.addTranslucent(s, xf, scale)
+		return
+	}
+	b.addOpaque(s, xf, scale)
+}
+
+// reaches reports whether s can paint any pixel of dst, so partial
+// redraws and region exports skip building geometry for strokes outside
+// them.
+func (b *strokeBatch) reaches(s *stroke, xf func(Vec2) Vec2) bool {
+	if b.dst == nil {
+		return true
+	}
+	pb := s.paintBounds(b.smooth)
+	lo := xf(Vec2{X: float32(pb.Min.X), Y: float32(pb.Min.Y)})
+	hi := xf(Vec2{X: float32(pb.Max.X), Y: float32(pb.Max.Y)})
+	r := image.Rect(
+		int(math.Floor(float64(lo.X)))-1, int(math.Floor(float64(lo.Y)))-1,
+		int(math.Ceil(float64(hi.X)))+1, int(math.Ceil(float64(hi.Y)))+1,
+	)
+	return r.Overlaps(b.dst.Bounds())
+}
+
+// addTranslucent renders the stroke opaquely into its own layer and then
+// composites that layer at the stroke's opacity, so overlapping segments
+// within the stroke do not build up darker spots.
+func (b *strokeBatch) addTranslucent(s *stroke, xf func(Vec2) Vec2, scale float64) {
+	b.flush()
+	pad := float32(s.Size*scale)/2 + 2
+	lo := xf(Vec2{X: float32(s.Bounds.Min.X), Y: float32(s.Bounds.Min.Y)})
+	hi := xf(Vec2{X: float32(s.Bounds.Max.X), Y: float32(s.Bounds.Max.Y)})
+	rect := image.Rect(
+		int(math.Floor(float64(lo.X-pad))), int(math.Floor(float64(lo.Y-pad))),
+		int(math.Ceil(float64(hi.X+pad)))+1, int(math.Ceil(float64(hi.Y+pad)))+1,
+	)
+	layer := ebiten.NewImage(rect.Dx(), rect.Dy())
+	defer layer.Dispose()
+
+	dx, dy := float32(rect.Min.X), float32(rect.Min.Y)
+	inner := &strokeBatch{dst: layer, smooth: b.smooth}
+	inner.addOpaque(s, func(p Vec2) Vec2 {
+		q := xf(p)
+		return Vec2{X: q.X - dx, Y: q.Y - dy}
+	}, scale)
+	inner.flush()
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(rect.Min.X), float64(rect.Min.Y))
+	op.ColorScale.ScaleAlpha(float32(s.alpha()))
+	b.dst.DrawImage(layer, op)
+}
+
+func (b *strokeBatch) addOpaque(s *stroke, xf func(Vec2) Vec2, scale float64) {
+	if s.flowRate() < 1 {
+		b.addStamps(s, xf, scale)
+		return
+	}
+	width := float32(s.Size * scale)
+	if len(b.vertices) > 0 && (width != b.width || !sameColor(b.clr, s.Color)) {
+		b.flush()
+	}
+	b.width = width
+	b.clr = s.Color
+
+	if s.Square {
+		prev := xf(s.Points[0])
+		b.appendSquare(prev)
+		for _, pt := range s.Points[1:] {
+			p := xf(pt)
+			forSquareStamps(prev, p, width, b.appendSquare)
+			prev = p
+		}
+		return
+	}
+	if len(s.Points) == 1 {
+		// A zero-length path draws nothing, so stretch the dot into a
+		// half-pixel line and let the round caps fill it in. Vector
+		// paths drop points within 0.01 of the previous one, which a
+		// smaller nudge can round down to in float32.
+		p := xf(s.Points[0])
+		var path vector.Path
+		path.MoveTo(p.X-0.25, p.Y)
+		path.LineTo(p.X+0.25, p.Y)
+		b.appendPath(&path)
+		return
+	}
+	if s.Widths != nil {
+		b.appendTapered(s, xf, scale)
+		return
+	}
+	chunk := strokeBatchChunk
+	if b.smooth {
+		chunk = smoothChunk
+	}
+	var curve []Vec2
+	for start := 0; start < len(s.Points)-1; start += chunk {
+		end := start + chunk
+		if end > len(s.Points)-1 {
+			end = len(s.Points) - 1
 		}
-		render(s)
+		var path vector.Path
+		p := xf(s.Points[start])
+		path.MoveTo(p.X, p.Y)
+		for i := start + 1; i <= end; i++ {
+			if b.smooth {
+				curve = smoothSegment(curve[:0], s.Points, i-1)
+				for _, q := range curve {
+					p = xf(q)
+					path.LineTo(p.X, p.Y)
+				}
+				continue
+			}
+			p = xf(s.Points[i])
+			path.LineTo(p.X, p.Y)
+		}
+		b.appendPath(&path)
 	}
+}
 
-	if g.current != nil && g.currentMode == g.mode {
-		render(g.current)
+// appendSquare adds an axis-aligned square of the batch width centered
+// on c.
+func (b *strokeBatch) appendSquare(c Vec2) {
+	if len(b.vertices)+4 > math.MaxUint16 {
+		b.flush()
+	}
+	h := b.width / 2
+	base := uint16(len(b.vertices))
+	b.vertices = append(b.vertices,
+		ebiten.Vertex{DstX: c.X - h, DstY: c.Y - h},
+		ebiten.Vertex{DstX: c.X + h, DstY: c.Y - h},
+		ebiten.Vertex{DstX: c.X + h, DstY: c.Y + h},
+		ebiten.Vertex{DstX: c.X - h, DstY: c.Y + h},
+	)
+	b.indices = append(b.indices, base, base+1, base+2, base, base+2, base+3)
+}
+
+// setCut switches between painting and cutting away coverage, flushing
+// first since the two need different blend modes.
+func (b *strokeBatch) setCut(cut bool) {
+	if cut != b.cut {
+		b.flush()
+		b.cut = cut
 	}
+}
 
-	for _, tb := range g.textBoxes {
-		g.drawTextBoxContent(tb)
+func (b *strokeBatch) appendPath(path *vector.Path) {
+	b.appendPathWidth(path, b.width)
+}
+
+// appendSegment adds the line from p to q at the given width.
+func (b *strokeBatch) appendSegment(p, q Vec2, width float32) {
+	var path vector.Path
+	path.MoveTo(p.X, p.Y)
+	path.LineTo(q.X, q.Y)
+	b.appendPathWidth(&path, width)
+}
+
+func (b *strokeBatch) appendPathWidth(path *vector.Path, width float32) {
+	op := &vector.StrokeOptions{Width: width, LineCap: vector.LineCapRound, LineJoin: vector.LineJoinRound}
+	b.scratchV, b.scratchI = path.AppendVerticesAndIndicesForStroke(b.scratchV[:0], b.scratchI[:0], op)
+	if len(b.vertices)+len(b.scratchV) > math.MaxUint16 {
+		b.flush()
+	}
+	base := uint16(len(b.vertices))
+	b.vertices = append(b.vertices, b.scratchV...)
+	for _, idx := range b.scratchI {
+		b.indices = append(b.indices, base+idx)
 	}
 }
 
-func (g *Game) drawSegment(a, b Vec2, size float64, clr color.Color) {
-	ca := g.worldToCanvas(a)
-	cb := g.worldToCanvas(b)
-	vector.StrokeLine(g.canvas, ca.X, ca.Y, cb.X, cb.Y, float32(size), clr, true)
-	radius := float32(size / 2)
-	vector.DrawFilledCircle(g.canvas, ca.X, ca.Y, radius, clr, true)
-	vector.DrawFilledCircle(g.canvas, cb.X, cb.Y, radius, clr, true)
+func (b *strokeBatch) flush() {
+	if len(b.vertices) == 0 {
+		return
+	}
+	r, g, bl, a := b.clr.RGBA()
+	for i := range b.vertices {
+		v := &b.vertices[i]
+		v.SrcX, v.SrcY = 1, 1
+		v.ColorR = float32(r) / 0xffff
+		v.ColorG = float32(g) / 0xffff
+		v.ColorB = float32(bl) / 0xffff
+		v.ColorA = float32(a) / 0xffff
+	}
+	op := &ebiten.DrawTrianglesOptions{ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha, AntiAlias: true}
+	if b.cut {
+		op.Blend = ebiten.BlendDestinationOut
+	}
+	// A batch without a destination only builds geometry, which lets
+	// benchmarks time it without a graphics context.
+	if b.dst != nil {
+		b.dst.DrawTriangles(b.vertices, b.indices, whiteSubImage, op)
+	}
+	b.draws++
+	b.vertices = b.vertices[:0]
+	b.indices = b.indices[:0]
 }
 
-func (g *Game) drawTextBoxContent(tb textBox) {
-	face := sizedFont(tb.Size)
-	pos := g.worldToCanvas(tb.Position)
+func renderTextBox(dst *ebiten.Image, tb textBox, xf func(Vec2) Vec2, scale float64) {
+	face := sizedFont(tb.Size * scale)
+	pos := xf(tb.Position)
 	ascent := face.Metrics().Ascent.Round()
-	text.Draw(g.canvas, tb.Text, face, int(pos.X), int(pos.Y)+ascent, color.White)
+	text.Draw(dst, tb.Text, face, int(pos.X), int(pos.Y)+ascent, color.White)
+}
+
+func (g *Game) drawSegment(a, b Vec2, size float64, clr color.Color) {
+	if g.current != nil && g.current.flowRate() < 1 {
+		g.drawStamps(a, b, float32(size))
+		return
+	}
+	if g.current != nil && g.current.Eraser {
+		g.cutSegment(g.worldToCanvas(a), g.worldToCanvas(b), float32(size))
+		return
+	}
+	dst := g.strokeTarget()
+	if g.current != nil && g.current.Square {
+		squareSegment(dst, g.worldToCanvas(a), g.worldToCanvas(b), float32(size), clr)
+		return
+	}
+	strokeSegment(dst, g.worldToCanvas(a), g.worldToCanvas(b), float32(size), clr)
+}
+
+// squareSegment stamps side-by-side squares from a to b, spaced closely
+// enough that they overlap into a solid blocky band.
+func squareSegment(dst *ebiten.Image, a, b Vec2, side float32, clr color.Color) {
+	forSquareStamps(a, b, side, func(c Vec2) {
+		vector.DrawFilledRect(dst, c.X-side/2, c.Y-side/2, side, side, clr, false)
+	})
+}
+
+// forSquareStamps calls stamp at b and at intervals of at most half a
+// square's side back toward a.
+func forSquareStamps(a, b Vec2, side float32, stamp func(Vec2)) {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	dist := float32(math.Hypot(float64(dx), float64(dy)))
+	step := side / 2
+	if step < 1 {
+		step = 1
+	}
+	n := int(math.Ceil(float64(dist / step)))
+	for i := 1; i <= n; i++ {
+		t := float32(i) / float32(n)
+		stamp(Vec2{X: a.X + dx*t, Y: a.Y + dy*t})
+	}
+	if n == 0 {
+		stamp(b)
+	}
+}
+
+func strokeSegment(dst *ebiten.Image, a, b Vec2, width float32, clr color.Color) {
+	vector.StrokeLine(dst, a.X, a.Y, b.X, b.Y, width, clr, true)
+	radius := width / 2
+	vector.DrawFilledCircle(dst, a.X, a.Y, radius, clr, true)
+	vector.DrawFilledCircle(dst, b.X, b.Y, radius, clr, true)
 }
 
 func defaultSaveDirectory() string {
@@ -949,9 +2094,11 @@ func (g *Game) confirmClear() {
 		message: "Clear the canvas?",
 		visible: true,
 		onConfirm: func() {
-			g.canvas.Fill(color.Black)
+			g.canvas.Fill(g.bgColor)
 			g.strokes = []*stroke{}
+			g.index.invalidate()
 			g.textBoxes = []textBox{}
+			g.baseImage = nil
 			g.current = nil
 			g.recordState()
 			g.ignoreInput = true
@@ -963,9 +2110,21 @@ func (g *Game) confirmClear() {
 func (g *Game) saveImage() {
 	now := time.Now().Format("20060102_150405")
 	g.save = saveDialog{
-		visible:   true,
-		directory: defaultSaveDirectory(),
-		filename:  fmt.Sprintf("drawing_%s.png", now),
+		visible:     true,
+		directory:   defaultSaveDirectory(),
+		sizes:       g.save.sizes,
+		srgb:        g.save.srgb,
+		mask:        g.save.mask,
+		transparent: g.save.transparent,
+		quality:     g.save.quality,
+		withPNG:     g.save.withPNG,
+	}
+	g.save.setFilename(fmt.Sprintf("drawing_%s.png", now))
+	if g.save.quality == 0 {
+		g.save.quality = 90
+	}
+	if g.save.sizes < 1 {
+		g.save.sizes = 1
 	}
 	g.save.loadEntries()
 }
@@ -1011,7 +2170,7 @@ func (g *Game) drawingBounds() (image.Rectangle, bool) {
 	}
 
 	for _, s := range g.strokes {
-		if s.Erased {
+		if !g.strokeVisible(s) {
 			continue
 		}
 		considerStroke(s)
@@ -1025,6 +2184,11 @@ func (g *Game) drawingBounds() (image.Rectangle, bool) {
 		considerText(t)
 	}
 
+	if base := g.baseRect(); !base.Empty() {
+		minX, minY = min(minX, base.Min.X), min(minY, base.Min.Y)
+		maxX, maxY = max(maxX, base.Max.X), max(maxY, base.Max.Y)
+	}
+
 	if minX == math.MaxInt32 {
 		return image.Rectangle{}, false
 	}
@@ -1040,93 +2204,363 @@ func (g *Game) saveToPath(path string) bool {
 
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		fmt.Println("Failed to create directory:", err)
+		g.save.failure = err.Error()
 		return false
 	}
 
-	bounds, ok := g.drawingBounds()
+	if isProjectPath(path) {
+		p, base := g.snapshotProject()
+		var preview exportJob
+		if bounds, ok := g.exportBounds(); ok && g.save.withPNG {
+			preview = exportJob{path: strings.TrimSuffix(path, filepath.Ext(path)) + ".png", img: g.flattenedImage(bounds)}
+		}
+		opts := g.exportOptions()
+		return g.startTask("Saving", func() func() {
+			if err := writeProject(path, p, base); err != nil {
+				return func() { g.saveFailed(err) }
+			}
+			fmt.Println("Saved project to", path)
+			if preview.img != nil {
+				if err := writeImage(preview.path, preview.img, opts); err != nil {
+					return func() { g.saveFailed(err) }
+				}
+				fmt.Println("Saved to", preview.path)
+			}
+			return nil
+		})
+	}
+
+	if isStrokeJSONPath(path) {
+		strokes := g.strokeJSON()
+		return g.startTask("Saving", func() func() {
+			if err := writeStrokeJSON(path, strokes); err != nil {
+				return func() { g.saveFailed(err) }
+			}
+			fmt.Println("Saved strokes to", path)
+			return nil
+		})
+	}
+
+	bounds, ok := g.exportBounds()
 	if !ok {
 		fmt.Println("Nothing to save")
+		g.save.failure = "the drawing is empty"
 		return false
 	}
 
+	img := g.flattenedImage(bounds)
+
+	// Rendering needs the GPU and stays on the game goroutine; encoding and
+	// writing the files happens in the background.
+	jobs := []exportJob{{path: path, img: img}}
+	ext := filepath.Ext(path)
+	for scale := 2; scale <= g.save.sizes; scale++ {
+		scaledPath := fmt.Sprintf("%s@%dx%s", strings.TrimSuffix(path, ext), scale, ext)
+		jobs = append(jobs, exportJob{path: scaledPath, img: g.renderRegion(bounds, float64(scale), false)})
+	}
+
+	if g.save.mask {
+		// The mask is always a lossless PNG, whatever the image's format.
+		maskPath := strings.TrimSuffix(path, ext) + "_mask.png"
+		rgba := g.renderRegion(bounds, 1, true)
+		gray := image.NewGray(rgba.Bounds())
+		draw.Draw(gray, gray.Bounds(), rgba, image.Point{}, draw.Src)
+		jobs = append(jobs, exportJob{path: maskPath, img: gray})
+	}
+	return g.writeImages(jobs, g.exportOptions(), g.saveFailed)
+}
+
+// flattenedImage returns the visible drawing within the world rectangle
+// bounds at 1x, as it would be saved.
+func (g *Game) flattenedImage(bounds image.Rectangle) *image.RGBA {
+	if g.save.transparent {
+		// The canvas has the background baked in, so re-render the
+		// strokes onto a clear image instead of copying its pixels.
+		return g.renderRegion(bounds, 1, false)
+	}
 	canvasRect := g.canvasRect()
-	subRect := image.Rect(bounds.Min.X-canvasRect.Min.X, bounds.Min.Y-canvasRect.Min.Y, bounds.Max.X-canvasRect.Min.X, bounds.Max.Y-canvasRect.Min.Y)
-	subImage := g.canvas.SubImage(subRect).(*ebiten.Image)
-	pixels := make([]byte, 4*subRect.Dx()*subRect.Dy())
-	subImage.ReadPixels(pixels)
-	img := image.NewRGBA(image.Rect(0, 0, subRect.Dx(), subRect.Dy()))
-	copy(img.Pix, pixels)
+	subRect := bounds.Sub(canvasRect.Min)
+	return readImage(g.canvas.SubImage(subRect).(*ebiten.Image))
+}
 
+// exportOptions are the encoder settings taken from the save dialog.
+type exportOptions struct {
+	srgb        bool
+	jpegQuality int
+	// background is what JPEG output is flattened onto, since JPEG has
+	// no alpha channel.
+	background color.Color
+}
+
+func (g *Game) exportOptions() exportOptions {
+	bg := g.bgColor
+	if isTransparent(bg) {
+		bg = color.White
+	}
+	return exportOptions{srgb: g.save.srgb, jpegQuality: int(g.save.quality), background: bg}
+}
+
+func isJPEGPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+// writeImage encodes img as JPEG or PNG depending on the file extension.
+func writeImage(path string, img image.Image, opts exportOptions) error {
 	f, err := os.Create(path)
 	if err != nil {
-		fmt.Println("Failed to save:", err)
-		return false
+		return err
 	}
 	defer f.Close()
-	if err := png.Encode(f, img); err != nil {
-		fmt.Println("Failed to save:", err)
-		return false
+	return encodeImage(f, img, isJPEGPath(path), opts)
+}
+
+// encodeImage writes img to w as JPEG or PNG with the export settings. It
+// is the file-free half of writeImage, for callers that want the bytes.
+func encodeImage(w io.Writer, img image.Image, asJPEG bool, opts exportOptions) error {
+	if !asJPEG {
+		return encodePNG(w, img, opts.srgb)
 	}
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(opts.background), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: opts.jpegQuality})
+}
 
-	fmt.Println("Saved to", path)
-	return true
+// renderRegion re-renders the world rectangle bounds from stroke data at
+// the given scale, so enlarged exports stay sharp instead of upsampling
+// the canvas pixels. The background is left clear when the save dialog's
+// transparent option is on.
+func (g *Game) renderRegion(bounds image.Rectangle, scale float64, mask bool) *image.RGBA {
+	w := int(math.Ceil(float64(bounds.Dx()) * scale))
+	h := int(math.Ceil(float64(bounds.Dy()) * scale))
+	dst := ebiten.NewImage(w, h)
+	defer dst.Dispose()
+	bg := g.bgColor
+	switch {
+	case mask:
+		bg = color.Black
+	case g.save.transparent:
+		bg = color.Transparent
+	}
+	dst.Fill(bg)
+
+	originX := float32(bounds.Min.X)
+	originY := float32(bounds.Min.Y)
+	k := float32(scale)
+	g.renderScene(dst, func(p Vec2) Vec2 {
+		return Vec2{X: (p.X - originX) * k, Y: (p.Y - originY) * k}
+	}, scale, mask)
+	return readImage(dst)
+}
+
+// readImage copies src's pixels back from the GPU into an RGBA image
+// anchored at the origin.
+func readImage(src *ebiten.Image) *image.RGBA {
+	size := src.Bounds().Size()
+	img := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	src.ReadPixels(img.Pix)
+	return img
+}
+
+// markDirty requests a full redraw on the next frame. Anything that changes
+// what is on screen without user input must call it.
+func (g *Game) markDirty() {
+	g.sceneDirty = true
+}
+
+// inputActive reports whether anything the user did this tick could have
+// changed the scene: cursor movement, buttons, keys, or the wheel.
+func (g *Game) inputActive() bool {
+	mx, my := ebiten.CursorPosition()
+	cursor := image.Pt(mx, my)
+	moved := cursor != g.lastCursor
+	g.lastCursor = cursor
+	if moved {
+		return true
+	}
+	for _, b := range []ebiten.MouseButton{ebiten.MouseButtonLeft, ebiten.MouseButtonRight, ebiten.MouseButtonMiddle} {
+		if ebiten.IsMouseButtonPressed(b) || inpututil.IsMouseButtonJustReleased(b) {
+			return true
+		}
+	}
+	if wx, wy := ebiten.Wheel(); wx != 0 || wy != 0 {
+		return true
+	}
+	return len(inpututil.AppendPressedKeys(nil)) > 0 || len(inpututil.AppendJustReleasedKeys(nil)) > 0
+}
+
+// cycleFPSCap steps the render cap through display rate, 30, and 15 FPS.
+func (g *Game) cycleFPSCap() {
+	switch g.fpsCap {
+	case 0:
+		g.fpsCap = 30
+	case 30:
+		g.fpsCap = 15
+	default:
+		g.fpsCap = 0
+	}
+}
+
+func (g *Game) setVsync(on bool) {
+	g.vsync = on
+	ebiten.SetVsyncEnabled(on)
+}
+
+func (g *Game) fpsCapLabel() string {
+	if g.fpsCap == 0 {
+		return "Display"
+	}
+	return fmt.Sprintf("%d FPS", g.fpsCap)
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
+	// The screen is not cleared between frames, so skipping a frame when
+	// nothing changed or under the cap leaves the previous one on display.
+	size := screen.Bounds().Size()
+	if size != g.lastScreen {
+		g.lastScreen = size
+		g.sceneDirty = true
+	}
+	if !g.sceneDirty {
+		return
+	}
+	if g.fpsCap > 0 {
+		now := time.Now()
+		if now.Sub(g.lastDraw) < time.Second/time.Duration(g.fpsCap) {
+			return
+		}
+		g.lastDraw = now
+	}
+	g.sceneDirty = false
+
 	w, _ := screen.Size()
-	screen.Fill(color.Black)
+	if isTransparent(g.bgColor) {
+		g.checker.draw(screen)
+	} else {
+		screen.Fill(g.bgColor)
+	}
 
 	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(-g.camera.X+g.canvasOrigin.X, -g.camera.Y+g.canvasOrigin.Y)
+	cam := g.viewCamera()
+	op.GeoM.Translate(-cam.X+g.canvasOrigin.X, -cam.Y+g.canvasOrigin.Y)
+	op.GeoM.Scale(g.zoom, g.zoom)
+	if g.zoom < 1 {
+		op.Filter = ebiten.FilterLinear
+	}
 	screen.DrawImage(g.canvas, op)
-
-	vector.DrawFilledRect(screen, 0, 0, float32(w), uiHeight, color.RGBA{20, 20, 20, 255}, false)
-	for _, b := range g.buttons {
-		b.draw(screen)
+	g.drawGrid(screen)
+	if g.fixedCanvas() {
+		g.drawPageFrame(screen)
 	}
-	g.sliders[0].draw(screen, "Brush Size")
-	g.sliders[1].draw(screen, "Eraser Size")
-	g.sliders[2].draw(screen, "Text Size")
-
-	status := "Mode: "
-	switch g.mode {
-	case modeDraw:
-		status += "Brush"
-	case modePixelErase:
-		status += "Pixel Eraser"
-	case modeStrokeErase:
-		status += "Stroke Eraser"
-	case modeText:
-		status += "Text"
+	g.drawArtboards(screen)
+	if !g.hideUI {
+		g.drawToolbar(screen, w)
+		g.drawCoordinates(screen)
 	}
-	drawText(screen, status, 20, uiHeight-20, color.White)
 
 	if g.confirm.visible {
 		g.confirm.draw(screen)
 	}
 
-	if g.mode == modePixelErase {
-		mx, my := ebiten.CursorPosition()
-		radius := float32(g.eraserSize / 2)
-		vector.StrokeCircle(screen, float32(mx), float32(my), radius, 1, color.RGBA{200, 200, 200, 200}, true)
-	}
-
-	if g.mode == modeStrokeErase {
-		mx, my := ebiten.CursorPosition()
-		radius := float32(g.eraserSize / 2)
-		vector.StrokeCircle(screen, float32(mx), float32(my), radius, 1, color.RGBA{200, 200, 200, 200}, true)
+	if g.resizing != nil {
+		radius := float32(g.worldSize(*g.resizing.value) / 2 * g.zoom)
+		vector.StrokeCircle(screen, g.resizeAnchor.X, g.resizeAnchor.Y, radius, 1.5, color.RGBA{120, 180, 240, 230}, true)
+	} else if tool, ok := g.tools[g.mode]; ok {
+		tool.Draw(g, screen)
 	}
 
 	if g.selectedText >= 0 && g.selectedText < len(g.textBoxes) {
 		rect := g.textBoxRect(g.textBoxes[g.selectedText])
-		offsetX := float32(rect.Min.X) - float32(g.camera.X)
-		offsetY := float32(rect.Min.Y) - float32(g.camera.Y)
-		vector.StrokeRect(screen, offsetX, offsetY, float32(rect.Dx()), float32(rect.Dy()), 2, color.RGBA{120, 180, 240, 220}, true)
+		offsetX, offsetY := g.screenFromWorld(float64(rect.Min.X), float64(rect.Min.Y))
+		k := float32(g.zoom)
+		vector.StrokeRect(screen, offsetX, offsetY, float32(rect.Dx())*k, float32(rect.Dy())*k, 2, color.RGBA{120, 180, 240, 220}, true)
+	}
+
+	if g.newDoc.visible {
+		g.drawNewDialog(screen)
+	}
+
+	if g.pageSize.visible {
+		g.drawPageSizeDialog(screen)
+	}
+
+	if g.showStats {
+		g.drawStatsPanel(screen)
+	}
+
+	if g.picker.visible {
+		g.drawColorPicker(screen)
+	}
+
+	if g.palette.visible {
+		g.drawPalette(screen)
 	}
 
 	if g.save.visible {
 		g.drawSaveDialog(screen)
 	}
+
+	g.drawBusy(screen)
+}
+
+// drawToolbar draws the buttons, sliders, and status row across the top
+// of a screen w pixels wide.
+func (g *Game) drawToolbar(screen *ebiten.Image, w int) {
+	vector.DrawFilledRect(screen, 0, 0, float32(w), uiHeight, color.RGBA{20, 20, 20, 255}, false)
+	for _, b := range g.buttons {
+		b.draw(screen)
+	}
+	g.sliders[0].draw(screen, "Brush Size")
+	g.sliders[1].draw(screen, "Eraser Size")
+	g.sliders[2].draw(screen, "Text Size")
+	g.sliders[3].draw(screen, "Opacity")
+	g.sliders[4].draw(screen, "Min Spacing")
+	g.sliders[5].draw(screen, "Grid")
+	g.sliders[6].draw(screen, "Flow")
+
+	status := "Mode: "
+	if tool, ok := g.tools[g.mode]; ok {
+		status += tool.Name()
+	}
+	if g.mode == modeArtboard {
+		status += " (" + g.boardRatio().String() + ")"
+	}
+	drawText(screen, status, 20, uiHeight-20, color.White)
+	drawText(screen, "Page: "+g.pageLabel(), 800, uiHeight-20, color.White)
+	vector.DrawFilledRect(screen, 232, uiHeight-30, 18, 18, g.secondColor, false)
+	vector.DrawFilledRect(screen, 222, uiHeight-38, 18, 18, g.brushColor, false)
+	vector.StrokeRect(screen, 222, uiHeight-38, 18, 18, 1, color.RGBA{120, 120, 120, 255}, false)
+	vector.DrawFilledRect(screen, 1206, 76, 18, 18, g.bgColor, false)
+	if isTransparent(g.bgColor) {
+		vector.StrokeLine(screen, 1206, 94, 1224, 76, 1.5, color.RGBA{220, 60, 60, 255}, true)
+	}
+	vector.StrokeRect(screen, 1206, 76, 18, 18, 1, color.RGBA{120, 120, 120, 255}, false)
+}
+
+// drawCoordinates shows the cursor's world position, the camera offset,
+// and the zoom in a strip along the bottom of the window. The toolbar's
+// status row has no room left for them.
+func (g *Game) drawCoordinates(screen *ebiten.Image) {
+	w, h := screen.Size()
+	mx, my := ebiten.CursorPosition()
+	readout := fmt.Sprintf("Camera: %.0f, %.0f   Zoom: %.0f%%", g.camera.X, g.camera.Y, g.zoom*100)
+	if my > g.toolbarHeight() {
+		p := g.worldFromScreen(mx, my)
+		readout = fmt.Sprintf("X: %.0f  Y: %.0f   ", p.X, p.Y) + readout
+	}
+	vector.DrawFilledRect(screen, 0, float32(h-22), float32(w), 22, color.RGBA{20, 20, 20, 200}, false)
+	drawText(screen, readout, 20, h-6, color.RGBA{200, 200, 200, 255})
+	if g.canvasAtLimit() {
+		drawText(screen, "Canvas at max size", w-480, h-6, color.RGBA{230, 180, 90, 255})
+	}
+	if g.notice != "" && time.Now().Before(g.noticeUntil) {
+		drawText(screen, fitText(g.notice, w-820), 340, h-6, color.RGBA{230, 120, 90, 255})
+	}
+	drawText(screen, "Layer: "+g.layerLabel(), w-300, h-6, color.RGBA{200, 200, 200, 255})
 }
 
 func (g *Game) drawSaveDialog(dst *ebiten.Image) {
@@ -1138,7 +2572,14 @@ func (g *Game) drawSaveDialog(dst *ebiten.Image) {
 	vector.DrawFilledRect(dst, 0, 0, float32(w), float32(h), color.RGBA{0, 0, 0, 120}, false)
 	vector.DrawFilledRect(dst, float32(x), float32(y), float32(dialogW), float32(dialogH), color.RGBA{30, 30, 30, 255}, false)
 	vector.DrawFilledRect(dst, float32(x), float32(y), float32(dialogW), 48, color.RGBA{50, 50, 50, 255}, false)
-	drawText(dst, "Save Image", x+20, y+32, color.White)
+	title, action := "Save Image", "Save"
+	if g.save.opening {
+		title, action = "Open Image or Project", "Open"
+	}
+	drawText(dst, title, x+20, y+32, color.White)
+	if g.save.failure != "" {
+		drawText(dst, fitText("Save failed: "+g.save.failure, dialogW-220), x+200, y+32, color.RGBA{230, 120, 90, 255})
+	}
 
 	drawText(dst, "Current Directory:", x+20, y+78, color.White)
 	vector.DrawFilledRect(dst, float32(x+120), float32(y+52), float32(dialogW-140), 36, color.RGBA{20, 20, 20, 255}, false)
@@ -1147,17 +2588,26 @@ func (g *Game) drawSaveDialog(dst *ebiten.Image) {
 	drawText(dst, "Filename:", x+20, y+106, color.White)
 	vector.DrawFilledRect(dst, float32(x+120), float32(y+80), float32(dialogW-140), 36, color.RGBA{20, 20, 20, 255}, false)
 	drawText(dst, g.save.filename, x+130, y+106, color.White)
+	g.drawFilenameCaret(dst, x+130, y+106)
 
-	listTop := y + 130
-	listBottom := y + dialogH - 140
-	vector.DrawFilledRect(dst, float32(x+20), float32(listTop), float32(dialogW-40), float32(listBottom-listTop), color.RGBA{15, 15, 15, 255}, false)
+	list := saveListRect(x, y, dialogW, dialogH)
+	vector.DrawFilledRect(dst, float32(list.Min.X), float32(list.Min.Y), float32(list.Dx()), float32(list.Dy()), color.RGBA{15, 15, 15, 255}, false)
 
-	entryHeight := 28
-	for i, e := range g.save.entries {
-		itemY := listTop + i*entryHeight
-		if itemY+entryHeight > listBottom {
+	entryHeight := saveEntryHeight
+	for row := 0; row < listRows(list); row++ {
+		i := g.save.scroll + row
+		if i >= len(g.save.entries) {
 			break
 		}
+		e := g.save.entries[i]
+		itemY := list.Min.Y + row*entryHeight
+		if i == g.save.selected {
+			highlight := color.RGBA{45, 45, 45, 255}
+			if g.save.listFocus {
+				highlight = color.RGBA{50, 80, 120, 255}
+			}
+			vector.DrawFilledRect(dst, float32(x+24), float32(itemY+2), float32(dialogW-48), float32(entryHeight-2), highlight, false)
+		}
 		label := e.name
 		if e.dir {
 			label = "📁 " + e.name
@@ -1166,11 +2616,49 @@ func (g *Game) drawSaveDialog(dst *ebiten.Image) {
 		}
 		drawText(dst, label, x+32, itemY+20, color.White)
 	}
+	if rows := listRows(list); len(g.save.entries) > rows {
+		// Scroll thumb sized and placed by the visible share of entries.
+		n := float32(len(g.save.entries))
+		thumbH := float32(list.Dy()) * float32(rows) / n
+		thumbY := float32(list.Min.Y) + float32(list.Dy())*float32(g.save.scroll)/n
+		vector.DrawFilledRect(dst, float32(list.Max.X-6), thumbY, 4, thumbH, color.RGBA{90, 90, 90, 255}, false)
+	}
 
 	vector.DrawFilledRect(dst, float32(x+20), float32(y+dialogH-60), 100, 40, color.RGBA{120, 70, 70, 255}, false)
-	vector.DrawFilledRect(dst, float32(x+dialogW-180), float32(y+dialogH-60), 160, 40, color.RGBA{70, 120, 70, 255}, false)
 	drawText(dst, "Cancel", x+52, y+dialogH-34, color.White)
-	drawText(dst, "Save", x+dialogW-122, y+dialogH-34, color.White)
+	if g.save.opening {
+		vector.DrawFilledRect(dst, float32(x+dialogW-180), float32(y+dialogH-60), 160, 40, color.RGBA{70, 120, 70, 255}, false)
+		drawText(dst, action, x+dialogW-122, y+dialogH-34, color.White)
+		return
+	}
+	vector.DrawFilledRect(dst, float32(x+140), float32(y+dialogH-60), 160, 40, color.RGBA{60, 60, 60, 255}, false)
+	sizesLabel := "Sizes: 1x"
+	for scale := 2; scale <= g.save.sizes; scale++ {
+		sizesLabel += fmt.Sprintf(", %dx", scale)
+	}
+	drawText(dst, sizesLabel, x+152, y+dialogH-34, color.White)
+	vector.DrawFilledRect(dst, float32(x+20), float32(y+dialogH-104), 160, 32, color.RGBA{60, 60, 60, 255}, false)
+	drawText(dst, "sRGB tag: "+onOff(g.save.srgb), x+32, y+dialogH-82, color.White)
+	vector.DrawFilledRect(dst, float32(x+200), float32(y+dialogH-104), 180, 32, color.RGBA{60, 60, 60, 255}, false)
+	drawText(dst, "Alpha mask: "+onOff(g.save.mask), x+212, y+dialogH-82, color.White)
+	vector.DrawFilledRect(dst, float32(x+400), float32(y+dialogH-104), 240, 32, color.RGBA{60, 60, 60, 255}, false)
+	drawText(dst, "Transparent background: "+onOff(g.save.transparent), x+412, y+dialogH-82, color.White)
+	saveFill := color.RGBA{70, 120, 70, 255}
+	saveLabel := color.Color(color.White)
+	if !g.save.targetWritable() {
+		saveFill = color.RGBA{60, 60, 60, 255}
+		saveLabel = color.RGBA{140, 140, 140, 255}
+		drawText(dst, "Directory is read-only", x+dialogW-400, y+dialogH-34, color.RGBA{230, 160, 90, 255})
+	}
+	if g.showQualitySlider() {
+		g.layoutQualitySlider(x, y, dialogH).draw(dst, "JPEG quality")
+	}
+	if g.showWithPNG() {
+		vector.DrawFilledRect(dst, float32(x+320), float32(y+dialogH-60), 180, 40, color.RGBA{60, 60, 60, 255}, false)
+		drawText(dst, "Also PNG: "+onOff(g.save.withPNG), x+332, y+dialogH-34, color.White)
+	}
+	vector.DrawFilledRect(dst, float32(x+dialogW-180), float32(y+dialogH-60), 160, 40, saveFill, false)
+	drawText(dst, action, x+dialogW-122, y+dialogH-34, saveLabel)
 }
 
 func distancePointToSegment(p, a, b Vec2) float64 {
@@ -1196,10 +2684,17 @@ func distancePointToSegment(p, a, b Vec2) float64 {
 
 func main() {
 	game := NewGame()
+	if len(os.Args) > 1 && isProjectPath(os.Args[1]) {
+		if err := game.loadProject(os.Args[1]); err != nil {
+			fmt.Println("Failed to open project:", err)
+		}
+	}
 	ebiten.SetWindowSize(1280, 720)
 	ebiten.SetWindowTitle("DraftIt - Infinite Canvas")
 	ebiten.SetWindowResizable(true)
+	ebiten.SetScreenClearedEveryFrame(false)
 	if err := ebiten.RunGame(game); err != nil {
 		panic(err)
 	}
+	game.saveToolSettings()
 }
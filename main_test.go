@@ -0,0 +1,142 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// zigzag returns a stroke of n points a few pixels apart, starting at
+// (x, y).
+func zigzag(x, y float32, n int, clr color.Color) *stroke {
+	s := &stroke{Size: 6, Color: clr}
+	for i := 0; i < n; i++ {
+		p := Vec2{X: x + float32(i)*3, Y: y + float32(i%2)*4}
+		s.Points = append(s.Points, p)
+		s.expandBounds(p)
+	}
+	return s
+}
+
+func identity(p Vec2) Vec2 { return p }
+
+// BenchmarkRebuildBatch times the triangle build for a rebuild of 1,000
+// strokes in runs of three colors. Drawing itself needs a graphics context,
+// so the batch has no destination; the draws/op metric shows how few
+// draw calls the batches issue against one per segment before batching.
+func BenchmarkRebuildBatch(b *testing.B) {
+	colors := []color.Color{color.White, color.Black, color.RGBA{200, 40, 40, 255}}
+	strokes := make([]*stroke, 1000)
+	segments := 0
+	for i := range strokes {
+		strokes[i] = zigzag(0, float32(i)*8, 50, colors[i*len(colors)/len(strokes)])
+		segments += len(strokes[i].Points) - 1
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	draws := 0
+	for i := 0; i < b.N; i++ {
+		batch := &strokeBatch{}
+		for _, s := range strokes {
+			batch.add(s, identity, 1)
+		}
+		batch.flush()
+		draws = batch.draws
+	}
+	b.ReportMetric(float64(draws), "draws/op")
+	b.ReportMetric(float64(segments), "segments/op")
+}
+
+// TestSinglePointDot checks that a one-point stroke still produces
+// triangles at coordinates where a tiny nudge would round away in
+// float32.
+func TestSinglePointDot(t *testing.T) {
+	for _, x := range []float32{0, 150, 5000, -5000} {
+		batch := &strokeBatch{}
+		s := &stroke{Points: []Vec2{{X: x, Y: 10}}, Size: 8, Color: color.White}
+		s.recomputeBounds()
+		batch.add(s, identity, 1)
+		if len(batch.indices) == 0 {
+			t.Errorf("dot at x=%v produced no triangles", x)
+		}
+		for _, v := range batch.vertices {
+			if math.Abs(float64(v.DstX-x)) > 5 {
+				t.Errorf("dot at x=%v has a vertex at x=%v", x, v.DstX)
+				break
+			}
+		}
+	}
+}
+
+// TestPanOutAndBack drags the view a long way from the origin at a zoom
+// that does not divide evenly, draws there, and drags back. The camera
+// and the world and canvas positions under the cursor must come back
+// bit for bit.
+func TestPanOutAndBack(t *testing.T) {
+	g := &Game{
+		zoom:         math.Pow(zoomStep, 3),
+		camera:       vec2d{X: 1e6 + 0.25, Y: -3e5},
+		canvasOrigin: vec2d{X: 1e6 - 1024, Y: -3e5 - 1024},
+	}
+	const mx, my, steps = 640, 400, 5000
+	start := g.camera
+	home := g.worldFromScreen(mx, my)
+
+	for i := 0; i < steps; i++ {
+		g.panBy(37, -11)
+	}
+	far := g.worldFromScreen(mx, my)
+	farCanvas := g.worldToCanvas(far)
+	for i := 0; i < steps; i++ {
+		g.panBy(-37, 11)
+	}
+	if g.camera != start {
+		t.Fatalf("camera after panning back = %+v, want %+v", g.camera, start)
+	}
+	if p := g.worldFromScreen(mx, my); p != home {
+		t.Errorf("point under cursor = %+v, want %+v", p, home)
+	}
+	if c, want := g.worldToCanvas(g.worldFromScreen(mx, my)), g.worldToCanvas(home); c != want {
+		t.Errorf("canvas point = %+v, want %+v", c, want)
+	}
+
+	// Going out again puts the cursor back over the far stroke.
+	for i := 0; i < steps; i++ {
+		g.panBy(37, -11)
+	}
+	if p := g.worldFromScreen(mx, my); p != far {
+		t.Errorf("far point = %+v, want %+v", p, far)
+	}
+	if c := g.worldToCanvas(far); c != farCanvas {
+		t.Errorf("far canvas point = %+v, want %+v", c, farCanvas)
+	}
+}
+
+// TestSetModeCommitsStroke switches tools mid-stroke: the stroke in
+// progress must land in the document and be undoable as one step.
+func TestSetModeCommitsStroke(t *testing.T) {
+	s := zigzag(10, 10, 8, color.Black)
+	g := &Game{mode: modeDraw, historyLimit: 10, current: s}
+	g.recordState()
+	g.setMode(modePixelErase)
+
+	if g.mode != modePixelErase {
+		t.Fatalf("mode = %v, want modePixelErase", g.mode)
+	}
+	if g.current != nil {
+		t.Error("current stroke not cleared")
+	}
+	if len(g.strokes) != 1 || g.strokes[0] != s {
+		t.Fatalf("strokes = %v, want the committed stroke", g.strokes)
+	}
+	if len(g.undoStack) != 2 {
+		t.Fatalf("undo stack has %d entries, want 2", len(g.undoStack))
+	}
+	if got := g.undoStack[0].strokes; len(got) != 0 {
+		t.Errorf("baseline holds %d strokes, want 0", len(got))
+	}
+	top := g.undoStack[1].strokes
+	if len(top) != 1 || len(top[0].Points) != len(s.Points) || top[0].Points[0] != s.Points[0] {
+		t.Errorf("undo entry strokes = %v, want a copy of the committed stroke", top)
+	}
+}
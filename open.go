@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// openFile shows the file browser in open mode.
+func (g *Game) openFile() {
+	g.save = saveDialog{
+		visible:     true,
+		opening:     true,
+		directory:   defaultSaveDirectory(),
+		sizes:       g.save.sizes,
+		srgb:        g.save.srgb,
+		mask:        g.save.mask,
+		transparent: g.save.transparent,
+		quality:     g.save.quality,
+		withPNG:     g.save.withPNG,
+	}
+	g.save.loadEntries()
+}
+
+// openPath opens a .draft project or starts a new document with a PNG as
+// its base image. Reading and decoding happen in the background.
+func (g *Game) openPath(path string) bool {
+	if isProjectPath(path) {
+		return g.startTask("Opening", func() func() {
+			lp, err := readProject(path)
+			if err != nil {
+				fmt.Println("Failed to open project:", err)
+				return nil
+			}
+			return func() { g.applyProject(lp) }
+		})
+	}
+	return g.startTask("Opening", func() func() {
+		img, err := decodePNGFile(path)
+		if err != nil {
+			fmt.Println("Failed to open image:", err)
+			return nil
+		}
+		return func() { g.openImage(img) }
+	})
+}
+
+func decodePNGFile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// openImage starts a new document with img at the world origin beneath
+// all strokes, growing the canvas to cover it.
+func (g *Game) openImage(img image.Image) {
+	g.newDocument(image.Point{})
+	g.baseImage = ebiten.NewImageFromImage(img)
+	size := img.Bounds().Size()
+	g.ensurePointVisible(Vec2{}, 0)
+	g.ensurePointVisible(Vec2{X: float32(size.X), Y: float32(size.Y)}, 0)
+	g.camera = vec2d{X: -20, Y: -(uiHeight + 20)}
+	g.undoStack = nil
+	g.redoStack = nil
+	g.rebuildCanvas()
+	g.recordState()
+}
+
+// baseRect is the world rectangle covered by the opened image.
+func (g *Game) baseRect() image.Rectangle {
+	if g.baseImage == nil {
+		return image.Rectangle{}
+	}
+	return g.baseImage.Bounds()
+}
+
+// drawBaseImage paints the opened image, if any, through the same world
+// transform renderScene uses for strokes.
+func (g *Game) drawBaseImage(dst *ebiten.Image, xf func(Vec2) Vec2, scale float64) {
+	if g.baseImage == nil {
+		return
+	}
+	origin := xf(Vec2{})
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(float64(origin.X), float64(origin.Y))
+	if scale != 1 {
+		op.Filter = ebiten.FilterLinear
+	}
+	dst.DrawImage(g.baseImage, op)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// canvasPreset is a document size offered when starting a new drawing.
+// A zero size means the default infinite canvas.
+type canvasPreset struct {
+	label string
+	size  image.Point
+}
+
+var canvasPresets = []canvasPreset{
+	{label: "Infinite canvas"},
+	{label: "1920 x 1080", size: image.Pt(1920, 1080)},
+	{label: "1280 x 720", size: image.Pt(1280, 720)},
+	{label: "1080 x 1080", size: image.Pt(1080, 1080)},
+	{label: "A4 portrait (1240 x 1754)", size: image.Pt(1240, 1754)},
+}
+
+type newDialog struct {
+	visible bool
+}
+
+func newDialogLayout(viewW, viewH int) (x, y, w, h int) {
+	w = 400
+	h = 100 + len(canvasPresets)*36
+	return (viewW - w) / 2, (viewH - h) / 2, w, h
+}
+
+func (g *Game) handleNewDialogInput(mx, my, viewW, viewH int, justClicked bool) {
+	if !justClicked {
+		return
+	}
+	x, y, w, h := newDialogLayout(viewW, viewH)
+	p := image.Pt(mx, my)
+	for i, preset := range canvasPresets {
+		row := image.Rect(x+20, y+56+i*36, x+w-20, y+88+i*36)
+		if rectContainsPoint(row, p) {
+			g.newDocument(preset.size)
+			g.newDoc.visible = false
+			g.ignoreInput = true
+			return
+		}
+	}
+	cancelRect := image.Rect(x+w-120, y+h-44, x+w-20, y+h-12)
+	if rectContainsPoint(cancelRect, p) {
+		g.newDoc.visible = false
+		g.ignoreInput = true
+	}
+}
+
+func (g *Game) drawNewDialog(dst *ebiten.Image) {
+	w, h := dst.Size()
+	x, y, dialogW, dialogH := newDialogLayout(w, h)
+	vector.DrawFilledRect(dst, 0, 0, float32(w), float32(h), color.RGBA{0, 0, 0, 120}, false)
+	vector.DrawFilledRect(dst, float32(x), float32(y), float32(dialogW), float32(dialogH), color.RGBA{30, 30, 30, 255}, false)
+	drawText(dst, "New drawing - choose a canvas size", x+20, y+34, color.White)
+	for i, preset := range canvasPresets {
+		rowY := y + 56 + i*36
+		vector.DrawFilledRect(dst, float32(x+20), float32(rowY), float32(dialogW-40), 32, color.RGBA{50, 50, 50, 255}, false)
+		drawText(dst, preset.label, x+32, rowY+22, color.White)
+	}
+	vector.DrawFilledRect(dst, float32(x+dialogW-120), float32(y+dialogH-44), 100, 32, color.RGBA{120, 70, 70, 255}, false)
+	drawText(dst, "Cancel", x+dialogW-100, y+dialogH-22, color.White)
+}
+
+// newDocument discards the current drawing and history and starts over
+// with the given document size. A zero size restores the infinite canvas.
+func (g *Game) newDocument(size image.Point) {
+	g.fixedSize = size
+	if size == (image.Point{}) {
+		g.canvas = ebiten.NewImage(initialCanvasSize, initialCanvasSize)
+		g.canvasOrigin = vec2d{X: -initialCanvasSize / 2, Y: -initialCanvasSize / 2}
+	} else {
+		g.canvas = ebiten.NewImage(size.X, size.Y)
+		g.canvasOrigin = vec2d{}
+	}
+	g.resetView()
+	g.strokes = []*stroke{}
+	g.index.invalidate()
+	g.textBoxes = []textBox{}
+	g.artboards = nil
+	g.layers = defaultLayers()
+	g.activeLayer = 0
+	g.baseImage = nil
+	g.current = nil
+	g.selectedText = -1
+	g.editingText = -1
+	g.draggingText = false
+	g.undoStack = nil
+	g.redoStack = nil
+	g.setNewestBelow(false)
+	g.rebuildCanvas()
+	g.recordState()
+}
+
+func (g *Game) fixedCanvas() bool {
+	return g.fixedSize != image.Point{}
+}
+
+// exportBounds is the world rectangle written on save: the whole page in
+// fixed-size mode, otherwise just the drawn content.
+func (g *Game) exportBounds() (image.Rectangle, bool) {
+	if g.fixedCanvas() {
+		return image.Rectangle{Max: g.fixedSize}, true
+	}
+	return g.drawingBounds()
+}
+
+// drawPageFrame shades the pasteboard around a fixed-size page and outlines
+// the page edges. It only touches the screen, so exports are unaffected.
+func (g *Game) drawPageFrame(screen *ebiten.Image) {
+	w, h := screen.Size()
+	px, py := g.screenFromWorld(0, 0)
+	pw := float32(float64(g.fixedSize.X) * g.zoom)
+	ph := float32(float64(g.fixedSize.Y) * g.zoom)
+	sw, sh := float32(w), float32(h)
+	dim := color.RGBA{50, 50, 55, 200}
+
+	vector.DrawFilledRect(screen, 0, 0, sw, py, dim, false)
+	vector.DrawFilledRect(screen, 0, py+ph, sw, sh-(py+ph), dim, false)
+	vector.DrawFilledRect(screen, 0, py, px, ph, dim, false)
+	vector.DrawFilledRect(screen, px+pw, py, sw-(px+pw), ph, dim, false)
+	vector.StrokeRect(screen, px-1, py-1, pw+2, ph+2, 2, color.RGBA{170, 170, 180, 255}, false)
+}
+
+// resetView returns the camera to where a new document starts: the world
+// origin at 1x, with a fixed page's top edge just below the toolbar.
+func (g *Game) resetView() {
+	g.camera = vec2d{}
+	if g.fixedCanvas() {
+		g.camera = vec2d{Y: -(uiHeight + 20)}
+	}
+	g.zoom = 1
+}
+
+func (g *Game) pageLabel() string {
+	if !g.fixedCanvas() {
+		return "Infinite"
+	}
+	return fmt.Sprintf("%d x %d", g.fixedSize.X, g.fixedSize.Y)
+}
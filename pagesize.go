@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// maxPageSide keeps resized pages within the texture sizes GPUs commonly
+// support.
+const maxPageSide = 8192
+
+// pageSizeDialog edits a fixed-size page's dimensions. Existing content is
+// either kept at its size (extend) or scaled uniformly to fit the new page
+// (scale), and anchored to the page's center or top-left corner.
+type pageSizeDialog struct {
+	visible       bool
+	width, height string
+	// focus is 0 while typing the width and 1 for the height.
+	focus  int
+	center bool
+	scale  bool
+}
+
+func (g *Game) openPageSizeDialog() {
+	if !g.fixedCanvas() {
+		fmt.Println("Only fixed-size pages can be resized")
+		return
+	}
+	g.pageSize = pageSizeDialog{
+		visible: true,
+		width:   strconv.Itoa(g.fixedSize.X),
+		height:  strconv.Itoa(g.fixedSize.Y),
+		center:  g.pageSize.center,
+		scale:   g.pageSize.scale,
+	}
+}
+
+func pageSizeLayout(viewW, viewH int) (x, y, w, h int) {
+	w, h = 420, 210
+	return (viewW - w) / 2, (viewH - h) / 2, w, h
+}
+
+func (g *Game) handlePageSizeInput(mx, my, viewW, viewH int, justClicked bool) {
+	d := &g.pageSize
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		d.visible = false
+		return
+	}
+	field := &d.width
+	if d.focus == 1 {
+		field = &d.height
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if r >= '0' && r <= '9' && len(*field) < 5 {
+			*field += string(r)
+		}
+	}
+	if keyRepeated(ebiten.KeyBackspace) && len(*field) > 0 {
+		*field = (*field)[:len(*field)-1]
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		d.focus = 1 - d.focus
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		g.confirmPageSize()
+		return
+	}
+	if !justClicked {
+		return
+	}
+
+	x, y, w, h := pageSizeLayout(viewW, viewH)
+	p := image.Pt(mx, my)
+	switch {
+	case rectContainsPoint(image.Rect(x+100, y+56, x+200, y+88), p):
+		d.focus = 0
+	case rectContainsPoint(image.Rect(x+290, y+56, x+390, y+88), p):
+		d.focus = 1
+	case rectContainsPoint(image.Rect(x+20, y+104, x+200, y+136), p):
+		d.center = !d.center
+	case rectContainsPoint(image.Rect(x+220, y+104, x+400, y+136), p):
+		d.scale = !d.scale
+	case rectContainsPoint(image.Rect(x+w-240, y+h-52, x+w-140, y+h-20), p):
+		d.visible = false
+		g.ignoreInput = true
+	case rectContainsPoint(image.Rect(x+w-120, y+h-52, x+w-20, y+h-20), p):
+		g.confirmPageSize()
+		g.ignoreInput = true
+	}
+}
+
+// confirmPageSize applies the typed size, leaving the dialog open when it
+// is out of range.
+func (g *Game) confirmPageSize() {
+	d := &g.pageSize
+	w, errW := strconv.Atoi(d.width)
+	h, errH := strconv.Atoi(d.height)
+	if errW != nil || errH != nil || w < 1 || h < 1 || w > maxPageSide || h > maxPageSide {
+		fmt.Printf("Page size must be between 1 and %d pixels on each side\n", maxPageSide)
+		return
+	}
+	d.visible = false
+	g.resizePage(image.Pt(w, h), d.center, d.scale)
+}
+
+func (g *Game) anchorLabel() string {
+	if g.pageSize.center {
+		return "Anchor: Center"
+	}
+	return "Anchor: Top-left"
+}
+
+func (g *Game) contentLabel() string {
+	if g.pageSize.scale {
+		return "Content: Scale"
+	}
+	return "Content: Extend"
+}
+
+func (g *Game) drawPageSizeDialog(dst *ebiten.Image) {
+	sw, sh := dst.Size()
+	x, y, w, h := pageSizeLayout(sw, sh)
+	d := g.pageSize
+	vector.DrawFilledRect(dst, 0, 0, float32(sw), float32(sh), color.RGBA{0, 0, 0, 120}, false)
+	vector.DrawFilledRect(dst, float32(x), float32(y), float32(w), float32(h), color.RGBA{30, 30, 30, 255}, false)
+	drawText(dst, "Resize page", x+20, y+34, color.White)
+
+	fields := []struct {
+		label, value string
+		lx, fx       int
+	}{{"Width:", d.width, x + 20, x + 100}, {"Height:", d.height, x + 220, x + 290}}
+	for i, f := range fields {
+		drawText(dst, f.label, f.lx, y+78, color.White)
+		vector.DrawFilledRect(dst, float32(f.fx), float32(y+56), 100, 32, color.RGBA{20, 20, 20, 255}, false)
+		if i == d.focus {
+			vector.StrokeRect(dst, float32(f.fx), float32(y+56), 100, 32, 1, color.RGBA{120, 180, 240, 255}, false)
+		}
+		drawText(dst, f.value, f.fx+10, y+78, color.White)
+	}
+
+	vector.DrawFilledRect(dst, float32(x+20), float32(y+104), 180, 32, color.RGBA{60, 60, 60, 255}, false)
+	drawText(dst, g.anchorLabel(), x+32, y+126, color.White)
+	vector.DrawFilledRect(dst, float32(x+220), float32(y+104), 180, 32, color.RGBA{60, 60, 60, 255}, false)
+	drawText(dst, g.contentLabel(), x+232, y+126, color.White)
+
+	vector.DrawFilledRect(dst, float32(x+w-240), float32(y+h-52), 100, 32, color.RGBA{120, 70, 70, 255}, false)
+	drawText(dst, "Cancel", x+w-220, y+h-30, color.White)
+	vector.DrawFilledRect(dst, float32(x+w-120), float32(y+h-52), 100, 32, color.RGBA{70, 120, 70, 255}, false)
+	drawText(dst, "Resize", x+w-100, y+h-30, color.White)
+}
+
+// resizePage changes the fixed page to size as one undoable step. With
+// scale set, content is scaled uniformly to fit; otherwise it keeps its
+// size. center anchors it to the middle of the page instead of the
+// top-left corner.
+func (g *Game) resizePage(size image.Point, center, scale bool) {
+	old := g.fixedSize
+	k := float32(1)
+	if scale {
+		k = min(float32(size.X)/float32(old.X), float32(size.Y)/float32(old.Y))
+	}
+	var off Vec2
+	if center {
+		off = Vec2{X: (float32(size.X) - float32(old.X)*k) / 2, Y: (float32(size.Y) - float32(old.Y)*k) / 2}
+	}
+	xf := func(p Vec2) Vec2 { return Vec2{X: p.X*k + off.X, Y: p.Y*k + off.Y} }
+
+	for _, s := range g.strokes {
+		for i, p := range s.Points {
+			s.Points[i] = xf(p)
+		}
+		s.Size *= float64(k)
+		for i := range s.Widths {
+			s.Widths[i] *= k
+		}
+		if s.Fill != nil {
+			s.Fill = s.Fill.transformed(k, off)
+		}
+		s.recomputeBounds()
+	}
+	for i := range g.textBoxes {
+		g.textBoxes[i].Position = xf(g.textBoxes[i].Position)
+		g.textBoxes[i].Size *= float64(k)
+	}
+	for i, a := range g.artboards {
+		lo, hi := xf(Vec2{X: float32(a.Rect.Min.X), Y: float32(a.Rect.Min.Y)}), xf(Vec2{X: float32(a.Rect.Max.X), Y: float32(a.Rect.Max.Y)})
+		g.artboards[i].Rect = image.Rect(int(lo.X), int(lo.Y), int(hi.X), int(hi.Y))
+	}
+	if g.baseImage != nil && (k != 1 || off != Vec2{}) {
+		// Snapshots share the old base image, so draw a moved copy
+		// rather than changing it.
+		base := ebiten.NewImage(size.X, size.Y)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(float64(k), float64(k))
+		op.GeoM.Translate(float64(off.X), float64(off.Y))
+		if k != 1 {
+			op.Filter = ebiten.FilterLinear
+		}
+		base.DrawImage(g.baseImage, op)
+		g.baseImage = base
+	}
+
+	g.index.invalidate()
+	g.fixedSize = size
+	g.canvas = ebiten.NewImage(size.X, size.Y)
+	g.canvasOrigin = vec2d{}
+	g.rebuildCanvas()
+	g.recordState()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+type command struct {
+	name string
+	run  func()
+}
+
+// commandPalette is a searchable list of every action the app offers,
+// opened with Ctrl+P and driven entirely from the keyboard.
+type commandPalette struct {
+	visible  bool
+	query    string
+	selected int
+}
+
+// commands lists the toolbar buttons plus keyboard-only actions, so new
+// buttons show up in the palette automatically.
+func (g *Game) commands() []command {
+	cmds := make([]command, 0, len(g.buttons)+3)
+	for _, b := range g.buttons {
+		cmds = append(cmds, command{name: b.label, run: b.onClick})
+	}
+	cmds = append(cmds,
+		command{name: "Save Project", run: g.saveProjectAs},
+		command{name: "Resize Page", run: g.openPageSizeDialog},
+		command{name: "Undo", run: g.undo},
+		command{name: "Redo", run: g.redo},
+		command{name: "Swap Colors", run: func() { g.brushColor, g.secondColor = g.secondColor, g.brushColor }},
+		command{name: "Pixel-Snapped Panning: " + onOff(g.pixelSnap), run: func() { g.pixelSnap = !g.pixelSnap }},
+		command{name: "Statistics Panel: " + onOff(g.showStats), run: func() { g.showStats = !g.showStats }},
+		command{name: "Vsync: " + onOff(g.vsync), run: func() { g.setVsync(!g.vsync) }},
+		command{name: "Frame Cap: " + g.fpsCapLabel(), run: g.cycleFPSCap},
+		command{name: "Smooth Strokes: " + onOff(g.smoothStrokes), run: g.toggleSmoothing},
+		command{name: "Velocity Width: " + onOff(g.velocityWidth), run: g.toggleVelocityWidth},
+		command{name: "Stroke Eraser Splits: " + onOff(g.splitStrokes), run: g.toggleSplitErase},
+		command{name: "Brush Size Lock: " + g.sizeLockLabel(), run: g.toggleSizeLock},
+		command{name: "Grid: " + onOff(g.showGrid), run: g.toggleGrid},
+		command{name: "Snap to Grid: " + onOff(g.snapToGrid), run: g.toggleSnap},
+		command{name: "Hide Interface", run: g.toggleUI},
+		command{name: "Artboard Ratio: " + g.boardRatio().String(), run: g.cycleBoardRatio},
+	)
+	cmds = append(cmds, g.layerCommands()...)
+	return append(cmds, g.tagCommands()...)
+}
+
+func (g *Game) filteredCommands() []command {
+	query := strings.ToLower(g.palette.query)
+	var out []command
+	for _, c := range g.commands() {
+		if strings.Contains(strings.ToLower(c.name), query) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (g *Game) openPalette() {
+	g.palette = commandPalette{visible: true}
+}
+
+func (g *Game) handlePaletteInput() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.palette.visible = false
+		return
+	}
+
+	chars := ebiten.AppendInputChars(nil)
+	if len(chars) > 0 {
+		g.palette.query += string(chars)
+		g.palette.selected = 0
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(g.palette.query) > 0 {
+		g.palette.query = g.palette.query[:len(g.palette.query)-1]
+		g.palette.selected = 0
+	}
+
+	matches := g.filteredCommands()
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) && g.palette.selected < len(matches)-1 {
+		g.palette.selected++
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) && g.palette.selected > 0 {
+		g.palette.selected--
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && g.palette.selected < len(matches) {
+		g.palette.visible = false
+		matches[g.palette.selected].run()
+	}
+}
+
+func (g *Game) drawPalette(dst *ebiten.Image) {
+	w, h := dst.Size()
+	paletteW := 420
+	x := (w - paletteW) / 2
+	y := uiHeight + 20
+	entryHeight := 28
+
+	matches := g.filteredCommands()
+	maxRows := (h - y - 60) / entryHeight
+	if maxRows < 1 {
+		maxRows = 1
+	}
+	rows := len(matches)
+	if rows > maxRows {
+		rows = maxRows
+	}
+	first := 0
+	if g.palette.selected >= rows {
+		first = g.palette.selected - rows + 1
+	}
+
+	vector.DrawFilledRect(dst, 0, 0, float32(w), float32(h), color.RGBA{0, 0, 0, 120}, false)
+	vector.DrawFilledRect(dst, float32(x), float32(y), float32(paletteW), float32(52+rows*entryHeight), color.RGBA{30, 30, 30, 255}, false)
+	vector.DrawFilledRect(dst, float32(x+10), float32(y+10), float32(paletteW-20), 32, color.RGBA{20, 20, 20, 255}, false)
+	drawText(dst, "> "+g.palette.query, x+18, y+32, color.White)
+
+	for i := 0; i < rows; i++ {
+		idx := first + i
+		itemY := y + 48 + i*entryHeight
+		if idx == g.palette.selected {
+			vector.DrawFilledRect(dst, float32(x+10), float32(itemY), float32(paletteW-20), float32(entryHeight), color.RGBA{50, 80, 120, 255}, false)
+		}
+		drawText(dst, matches[idx].name, x+18, itemY+20, color.White)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+)
+
+// pngIHDREnd is the offset just past the signature and IHDR chunk, which
+// image/png always writes first. Ancillary chunks that must precede the
+// image data are spliced in here.
+const pngIHDREnd = 8 + 4 + 4 + 13 + 4
+
+// pngChunk serializes one PNG chunk: length, type, data, and CRC.
+func pngChunk(typ string, data []byte) []byte {
+	out := make([]byte, 0, 12+len(data))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(data)))
+	out = append(out, typ...)
+	out = append(out, data...)
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	return binary.BigEndian.AppendUint32(out, crc.Sum32())
+}
+
+// srgbChunks marks the image as sRGB with perceptual intent, plus the
+// matching gAMA value the PNG spec recommends for older decoders.
+func srgbChunks() []byte {
+	var out []byte
+	out = append(out, pngChunk("sRGB", []byte{0})...)
+	out = append(out, pngChunk("gAMA", binary.BigEndian.AppendUint32(nil, 45455))...)
+	return out
+}
+
+// encodePNG writes img as a PNG, optionally tagged as sRGB so color-managed
+// viewers do not guess at the color space.
+func encodePNG(w io.Writer, img image.Image, tagSRGB bool) error {
+	if !tagSRGB {
+		return png.Encode(w, img)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	encoded := buf.Bytes()
+	if _, err := w.Write(encoded[:pngIHDREnd]); err != nil {
+		return err
+	}
+	if _, err := w.Write(srgbChunks()); err != nil {
+		return err
+	}
+	_, err := w.Write(encoded[pngIHDREnd:])
+	return err
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// projectExt marks files written by saveProject. The save dialog writes a
+// project instead of a PNG when the filename carries it.
+const projectExt = ".draft"
+
+const projectVersion = 1
+
+// projectFile is the JSON form of a document. Colors are stored as RGBA
+// components because color.Color is an interface.
+type projectFile struct {
+	Version      int              `json:"version"`
+	FixedSize    image.Point      `json:"fixedSize"`
+	CanvasOrigin vec2d            `json:"canvasOrigin"`
+	CanvasSize   image.Point      `json:"canvasSize"`
+	Background   *projectColor    `json:"background"`
+	Strokes      []projectStroke  `json:"strokes"`
+	TextBoxes    []projectTextBox `json:"textBoxes"`
+	Artboards    []artboard       `json:"artboards"`
+	Layers       []layer          `json:"layers,omitempty"`
+	NewestBelow  bool             `json:"newestBelow,omitempty"`
+	// BaseImage is the opened PNG, if any, stored as PNG bytes.
+	BaseImage []byte `json:"baseImage,omitempty"`
+}
+
+type projectColor [4]uint8
+
+func toProjectColor(c color.Color) projectColor {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return projectColor{rgba.R, rgba.G, rgba.B, rgba.A}
+}
+
+func (c projectColor) color() color.Color {
+	return color.RGBA{c[0], c[1], c[2], c[3]}
+}
+
+type projectStroke struct {
+	Points  [][2]float32 `json:"points"`
+	Size    float64      `json:"size"`
+	Color   projectColor `json:"color"`
+	Opacity float64      `json:"opacity,omitempty"`
+	Flow    float64      `json:"flow,omitempty"`
+	Erased  bool         `json:"erased,omitempty"`
+	Eraser  bool         `json:"eraser,omitempty"`
+	Square  bool         `json:"square,omitempty"`
+	Tags    []string     `json:"tags,omitempty"`
+	Widths  []float32    `json:"widths,omitempty"`
+	Layer   int          `json:"layer,omitempty"`
+	// Fill is a paint-bucket fill's mask as PNG bytes, placed at FillAt.
+	Fill   []byte `json:"fill,omitempty"`
+	FillAt [2]int `json:"fillAt,omitempty"`
+}
+
+type projectTextBox struct {
+	Position [2]float32 `json:"position"`
+	Text     string     `json:"text"`
+	Size     float64    `json:"size"`
+}
+
+func isProjectPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), projectExt)
+}
+
+// saveProject writes the document's strokes, text, and artboards so it can
+// be reopened with loadProject.
+func (g *Game) saveProject(path string) error {
+	p, base := g.snapshotProject()
+	return writeProject(path, p, base)
+}
+
+// snapshotProject copies the document into its file form. It reads GPU
+// pixels, so it runs on the game goroutine; the result is safe to hand to
+// writeProject on another one.
+func (g *Game) snapshotProject() (projectFile, image.Image) {
+	bg := toProjectColor(g.bgColor)
+	p := projectFile{
+		Version:      projectVersion,
+		FixedSize:    g.fixedSize,
+		CanvasOrigin: g.canvasOrigin,
+		CanvasSize:   g.canvas.Bounds().Size(),
+		Background:   &bg,
+		Strokes:      make([]projectStroke, 0, len(g.strokes)),
+		TextBoxes:    make([]projectTextBox, 0, len(g.textBoxes)),
+		Artboards:    copyArtboards(g.artboards),
+		Layers:       copyLayers(g.layers),
+		NewestBelow:  g.newestBelow,
+	}
+	for _, s := range g.strokes {
+		ps := projectStroke{
+			Points:  make([][2]float32, len(s.Points)),
+			Size:    s.Size,
+			Color:   toProjectColor(s.Color),
+			Opacity: s.Opacity,
+			Flow:    s.Flow,
+			Erased:  s.Erased,
+			Eraser:  s.Eraser,
+			Square:  s.Square,
+			Tags:    s.Tags,
+			Widths:  s.Widths,
+			Layer:   s.Layer,
+		}
+		for i, pt := range s.Points {
+			ps.Points[i] = [2]float32{pt.X, pt.Y}
+		}
+		if s.Fill != nil {
+			var err error
+			if ps.Fill, ps.FillAt, err = encodeFill(s.Fill); err != nil {
+				fmt.Println("Failed to encode fill:", err)
+				continue
+			}
+		}
+		p.Strokes = append(p.Strokes, ps)
+	}
+	for _, tb := range g.textBoxes {
+		p.TextBoxes = append(p.TextBoxes, projectTextBox{
+			Position: [2]float32{tb.Position.X, tb.Position.Y},
+			Text:     tb.Text,
+			Size:     tb.Size,
+		})
+	}
+	var base image.Image
+	if g.baseImage != nil {
+		base = readImage(g.baseImage)
+	}
+	return p, base
+}
+
+func writeProject(path string, p projectFile, base image.Image) error {
+	if base != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, base); err != nil {
+			return err
+		}
+		p.BaseImage = buf.Bytes()
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// loadedProject is a decoded project file waiting to be applied.
+type loadedProject struct {
+	file      projectFile
+	strokes   []*stroke
+	textBoxes []textBox
+	base      image.Image
+}
+
+// loadProject replaces the current document with the one stored at path.
+// Undo history starts fresh from the loaded state.
+func (g *Game) loadProject(path string) error {
+	lp, err := readProject(path)
+	if err != nil {
+		return err
+	}
+	g.applyProject(lp)
+	return nil
+}
+
+// readProject reads and decodes a project file without touching Game
+// state, so it can run off the game goroutine.
+func readProject(path string) (*loadedProject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p projectFile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if p.Version > projectVersion {
+		return nil, fmt.Errorf("%s was written by a newer version (format %d)", path, p.Version)
+	}
+
+	if len(p.Layers) == 0 {
+		// Written before layers existed.
+		p.Layers = defaultLayers()
+	}
+	lp := &loadedProject{
+		file:      p,
+		strokes:   make([]*stroke, 0, len(p.Strokes)),
+		textBoxes: make([]textBox, 0, len(p.TextBoxes)),
+	}
+	for _, ps := range p.Strokes {
+		if len(ps.Points) == 0 {
+			continue
+		}
+		s := &stroke{
+			Size:    ps.Size,
+			Color:   ps.Color.color(),
+			Opacity: ps.Opacity,
+			Flow:    ps.Flow,
+			Erased:  ps.Erased,
+			Eraser:  ps.Eraser,
+			Square:  ps.Square,
+			Tags:    ps.Tags,
+			Points:  make([]Vec2, len(ps.Points)),
+		}
+		if ps.Layer > 0 && ps.Layer < len(p.Layers) {
+			s.Layer = ps.Layer
+		}
+		for i, pt := range ps.Points {
+			s.Points[i] = Vec2{X: pt[0], Y: pt[1]}
+		}
+		if len(ps.Widths) == len(ps.Points) {
+			s.Widths = ps.Widths
+		}
+		if len(ps.Fill) > 0 {
+			fill, err := decodeFill(ps.Fill, ps.FillAt)
+			if err != nil {
+				return nil, fmt.Errorf("fill: %w", err)
+			}
+			s.Fill = fill
+		}
+		s.recomputeBounds()
+		lp.strokes = append(lp.strokes, s)
+	}
+	for _, pt := range p.TextBoxes {
+		lp.textBoxes = append(lp.textBoxes, textBox{Position: Vec2{X: pt.Position[0], Y: pt.Position[1]}, Text: pt.Text, Size: pt.Size})
+	}
+	if len(p.BaseImage) > 0 {
+		img, err := png.Decode(bytes.NewReader(p.BaseImage))
+		if err != nil {
+			return nil, fmt.Errorf("base image: %w", err)
+		}
+		lp.base = img
+	}
+	return lp, nil
+}
+
+func (g *Game) applyProject(lp *loadedProject) {
+	p := lp.file
+	g.newDocument(p.FixedSize)
+	if p.CanvasSize.X > 0 && p.CanvasSize.Y > 0 {
+		g.canvas = ebiten.NewImage(p.CanvasSize.X, p.CanvasSize.Y)
+		g.canvasOrigin = p.CanvasOrigin
+	}
+	// Every project is saved with its background; keep the session's for
+	// a file missing one.
+	if p.Background != nil {
+		g.bgColor = p.Background.color()
+	}
+	g.strokes = lp.strokes
+	g.index.invalidate()
+	g.textBoxes = lp.textBoxes
+	g.artboards = p.Artboards
+	g.layers = p.Layers
+	g.setNewestBelow(p.NewestBelow)
+	if lp.base != nil {
+		g.baseImage = ebiten.NewImageFromImage(lp.base)
+	}
+	g.undoStack = nil
+	g.redoStack = nil
+	g.rebuildCanvas()
+	g.recordState()
+}
+
+// saveProjectAs opens the save dialog with a project filename in place of
+// the default PNG name.
+func (g *Game) saveProjectAs() {
+	g.saveImage()
+	g.save.setFilename(strings.TrimSuffix(g.save.filename, filepath.Ext(g.save.filename)) + projectExt)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Scroll modes, chosen with the config's "scroll" key. scrollZoom suits
+// a mouse wheel; scrollPan suits a trackpad's two-finger scroll.
+const (
+	scrollZoom = "zoom"
+	scrollPan  = "pan"
+)
+
+// scrollPanSpeed is how many screen pixels one wheel unit pans.
+const scrollPanSpeed = 40
+
+func parseScrollMode(s string) string {
+	switch s {
+	case "", scrollZoom:
+		return scrollZoom
+	case scrollPan:
+		return scrollPan
+	}
+	fmt.Printf("Ignoring scroll mode %q: want %q or %q\n", s, scrollZoom, scrollPan)
+	return scrollZoom
+}
+
+// handleScroll applies wheel and trackpad scrolling over the canvas.
+// Horizontal scroll always pans. Vertical scroll zooms around the cursor
+// in zoom mode and pans in pan mode, where holding Ctrl (Cmd on macOS)
+// zooms instead. Precision trackpads on Windows report a pinch as
+// Ctrl+scroll, so it zooms as well.
+func (g *Game) handleScroll(mx, my int) {
+	wx, wy := ebiten.Wheel()
+	if my <= g.toolbarHeight() || (wx == 0 && wy == 0) {
+		return
+	}
+	g.camera.X -= wx * scrollPanSpeed / g.zoom
+	if wy == 0 {
+		return
+	}
+	ctrl := ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)
+	if g.scrollMode == scrollPan && !ctrl {
+		g.camera.Y -= wy * scrollPanSpeed / g.zoom
+		return
+	}
+	g.zoomAt(mx, my, g.zoom*math.Pow(zoomStep, wy))
+}
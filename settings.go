@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// toolSettings is the toolbar state saved to the config file on exit and
+// restored on the next launch.
+type toolSettings struct {
+	Tool         string  `json:"tool"`
+	BrushSize    float64 `json:"brushSize"`
+	EraserSize   float64 `json:"eraserSize"`
+	TextSize     float64 `json:"textSize"`
+	Opacity      float64 `json:"opacity"`
+	Flow         float64 `json:"flow,omitempty"`
+	MinSpacing   float64 `json:"minSpacing"`
+	BrushColor   string  `json:"brushColor"`
+	SecondColor  string  `json:"secondColor"`
+	SquareEraser bool    `json:"squareEraser,omitempty"`
+	Smooth       bool    `json:"smooth,omitempty"`
+	Velocity     bool    `json:"velocityWidth,omitempty"`
+	SplitStrokes bool    `json:"splitStrokes,omitempty"`
+	ScreenSizes  bool    `json:"screenSizes,omitempty"`
+	Grid         bool    `json:"grid,omitempty"`
+	GridSize     float64 `json:"gridSize,omitempty"`
+	Snap         bool    `json:"snap,omitempty"`
+	Tag          string  `json:"tag,omitempty"`
+}
+
+func (g *Game) toolSettings() *toolSettings {
+	ts := &toolSettings{
+		BrushSize:    g.brushSize,
+		EraserSize:   g.eraserSize,
+		TextSize:     g.textSize,
+		Opacity:      g.brushOpacity,
+		Flow:         g.brushFlow,
+		MinSpacing:   g.minSpacing,
+		BrushColor:   rgbHex(color.RGBAModel.Convert(g.brushColor).(color.RGBA)),
+		SecondColor:  rgbHex(color.RGBAModel.Convert(g.secondColor).(color.RGBA)),
+		SquareEraser: g.squareEraser,
+		Smooth:       g.smoothStrokes,
+		Velocity:     g.velocityWidth,
+		SplitStrokes: g.splitStrokes,
+		ScreenSizes:  g.screenSizes,
+		Grid:         g.showGrid,
+		GridSize:     g.gridSize,
+		Snap:         g.snapToGrid,
+		Tag:          g.activeTag,
+	}
+	if tool, ok := g.tools[g.mode]; ok {
+		ts.Tool = tool.Name()
+	}
+	return ts
+}
+
+// applyToolSettings restores saved toolbar state. It runs before setupUI
+// so button labels pick up the restored values; unknown tools, tags, and
+// colors are ignored.
+func (g *Game) applyToolSettings(ts *toolSettings) {
+	if ts == nil {
+		return
+	}
+	for mode, tool := range g.tools {
+		if tool.Name() == ts.Tool {
+			g.mode = mode
+			g.currentMode = mode
+		}
+	}
+	g.brushSize = ts.BrushSize
+	g.eraserSize = ts.EraserSize
+	g.textSize = ts.TextSize
+	g.brushOpacity = ts.Opacity
+	if ts.Flow > 0 {
+		g.brushFlow = ts.Flow
+	}
+	g.minSpacing = ts.MinSpacing
+	if c, err := parseHex(ts.BrushColor); err == nil {
+		g.brushColor = c
+	}
+	if c, err := parseHex(ts.SecondColor); err == nil {
+		g.secondColor = c
+	}
+	g.squareEraser = ts.SquareEraser
+	g.smoothStrokes = ts.Smooth
+	g.velocityWidth = ts.Velocity
+	g.splitStrokes = ts.SplitStrokes
+	g.screenSizes = ts.ScreenSizes
+	g.showGrid = ts.Grid
+	g.snapToGrid = ts.Snap
+	if ts.GridSize > 0 {
+		g.gridSize = ts.GridSize
+	}
+	for _, tag := range strokeTagPresets {
+		if tag == ts.Tag {
+			g.activeTag = tag
+		}
+	}
+}
+
+// clampSliders pulls every slider value back into its range, so hand-edited
+// or stale settings cannot push a knob off its track.
+func (g *Game) clampSliders() {
+	for _, s := range g.sliders {
+		*s.value = math.Max(s.min, math.Min(s.max, *s.value))
+	}
+}
+
+// saveToolSettings records the toolbar state in the config file. A config
+// the user has broken by hand is left alone rather than overwritten.
+func (g *Game) saveToolSettings() {
+	err := updateConfig(func(cfg *appConfig) {
+		cfg.Tools = g.toolSettings()
+	})
+	if err != nil {
+		fmt.Println("Failed to save tool settings:", err)
+	}
+}
+
+func parseHex(s string) (color.RGBA, error) {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("bad color %q", s)
+	}
+	return color.RGBA{r, g, b, 255}, nil
+}
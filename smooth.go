@@ -0,0 +1,59 @@
+package main
+
+import "math"
+
+// smoothChunk is strokeBatchChunk for smoothed strokes, whose segments
+// each expand into several sub-points.
+const smoothChunk = 32
+
+// catmullRom evaluates the uniform Catmull-Rom spline through p1 and p2,
+// with p0 and p3 as the neighboring control points, at t in [0, 1].
+func catmullRom(p0, p1, p2, p3 Vec2, t float32) Vec2 {
+	t2 := t * t
+	t3 := t2 * t
+	f := func(a, b, c, d float32) float32 {
+		return 0.5 * (2*b + (c-a)*t + (2*a-5*b+4*c-d)*t2 + (3*b-a-3*c+d)*t3)
+	}
+	return Vec2{X: f(p0.X, p1.X, p2.X, p3.X), Y: f(p0.Y, p1.Y, p2.Y, p3.Y)}
+}
+
+// smoothSegment appends the curve from pts[i] to pts[i+1] to dst, not
+// including pts[i] itself. The first and last segments repeat their
+// endpoint in place of the missing neighbor. Segments are split roughly
+// every 4 pixels.
+func smoothSegment(dst, pts []Vec2, i int) []Vec2 {
+	p1, p2 := pts[i], pts[i+1]
+	p0, p3 := p1, p2
+	if i > 0 {
+		p0 = pts[i-1]
+	}
+	if i+2 < len(pts) {
+		p3 = pts[i+2]
+	}
+	length := math.Hypot(float64(p2.X-p1.X), float64(p2.Y-p1.Y))
+	n := int(math.Ceil(length / 4))
+	n = max(1, min(n, 16))
+	for k := 1; k <= n; k++ {
+		dst = append(dst, catmullRom(p0, p1, p2, p3, float32(k)/float32(n)))
+	}
+	return dst
+}
+
+// drawSmoothSegment paints the curved segment i of the live stroke onto
+// the canvas.
+func (g *Game) drawSmoothSegment(i int) {
+	s := g.current
+	prev := s.Points[i]
+	wa, wb := s.widthAt(i), s.widthAt(i+1)
+	curve := smoothSegment(nil, s.Points, i)
+	for j, q := range curve {
+		t := (float32(j) + 0.5) / float32(len(curve))
+		g.drawSegment(prev, q, float64(wa+(wb-wa)*t), g.liveColor())
+		prev = q
+	}
+}
+
+func (g *Game) toggleSmoothing() {
+	g.smoothStrokes = !g.smoothStrokes
+	g.rebuildCanvas()
+}
@@ -0,0 +1,35 @@
+package main
+
+import "math"
+
+// snapPoint rounds p to the nearest grid intersection when snapping is
+// on. Snapping uses the grid spacing whether or not the grid is shown.
+func (g *Game) snapPoint(p Vec2) Vec2 {
+	if !g.snapToGrid || g.gridSize <= 0 {
+		return p
+	}
+	step := g.gridSize
+	return Vec2{
+		X: float32(math.Round(float64(p.X)/step) * step),
+		Y: float32(math.Round(float64(p.Y)/step) * step),
+	}
+}
+
+// snapStrokeEnd moves the live stroke's last point onto the grid and
+// reports whether it moved. Together with the snapped first point this
+// puts both ends of a stroke, and of a straightened line, on the grid.
+func (g *Game) snapStrokeEnd() bool {
+	pts := g.current.Points
+	last := pts[len(pts)-1]
+	p := g.snapPoint(last)
+	if p == last {
+		return false
+	}
+	pts[len(pts)-1] = p
+	g.current.expandBounds(p)
+	return true
+}
+
+func (g *Game) toggleSnap() {
+	g.snapToGrid = !g.snapToGrid
+}
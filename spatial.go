@@ -0,0 +1,98 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// indexCell is the side, in world pixels, of a strokeIndex cell.
+const indexCell = 256
+
+// strokeIndex buckets strokes by the grid cells their painted area
+// touches, so eraser hit tests only look at strokes near the cursor. It
+// is rebuilt lazily from g.strokes after anything replaces or moves
+// strokes wholesale; appends and splits keep it up to date in place.
+type strokeIndex struct {
+	cells map[image.Point][]*stroke
+	valid bool
+}
+
+func (ix *strokeIndex) invalidate() {
+	ix.cells = nil
+	ix.valid = false
+}
+
+// cellRange returns the cells covering the world rectangle r.
+func cellRange(r image.Rectangle) image.Rectangle {
+	floorDiv := func(v int) int { return int(math.Floor(float64(v) / indexCell)) }
+	return image.Rect(floorDiv(r.Min.X), floorDiv(r.Min.Y), floorDiv(r.Max.X-1)+1, floorDiv(r.Max.Y-1)+1)
+}
+
+// strokeCells is the range of cells s is filed under. Smoothed bounds
+// are used whether or not smoothing is on, so toggling it never leaves a
+// stroke filed too narrowly.
+func strokeCells(s *stroke) image.Rectangle {
+	return cellRange(s.paintBounds(true))
+}
+
+func (ix *strokeIndex) insert(s *stroke) {
+	if !ix.valid {
+		return
+	}
+	r := strokeCells(s)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := image.Pt(x, y)
+			ix.cells[c] = append(ix.cells[c], s)
+		}
+	}
+}
+
+func (ix *strokeIndex) remove(s *stroke) {
+	if !ix.valid {
+		return
+	}
+	r := strokeCells(s)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := image.Pt(x, y)
+			list := ix.cells[c]
+			for i, t := range list {
+				if t == s {
+					list = append(list[:i], list[i+1:]...)
+					break
+				}
+			}
+			if len(list) == 0 {
+				delete(ix.cells, c)
+			} else {
+				ix.cells[c] = list
+			}
+		}
+	}
+}
+
+// strokesNear returns each stroke that could paint within radius of p,
+// once, for hit tests to check precisely.
+func (g *Game) strokesNear(p Vec2, radius float64) map[*stroke]bool {
+	ix := &g.index
+	if !ix.valid {
+		ix.cells = map[image.Point][]*stroke{}
+		ix.valid = true
+		for _, s := range g.strokes {
+			ix.insert(s)
+		}
+	}
+	r := int(math.Ceil(radius))
+	x, y := int(math.Floor(float64(p.X))), int(math.Floor(float64(p.Y)))
+	cells := cellRange(image.Rect(x-r, y-r, x+r+1, y+r+1))
+	near := map[*stroke]bool{}
+	for cy := cells.Min.Y; cy < cells.Max.Y; cy++ {
+		for cx := cells.Min.X; cx < cells.Max.X; cx++ {
+			for _, s := range ix.cells[image.Pt(cx, cy)] {
+				near[s] = true
+			}
+		}
+	}
+	return near
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// splitStroke cuts everything within radius of c out of s and returns the
+// surviving pieces in drawing order. touched is false when the eraser
+// missed the stroke, in which case pieces is nil.
+func splitStroke(s *stroke, c Vec2, radius float64) (pieces []*stroke, touched bool) {
+	if !s.hit(c, radius-s.Size/2) {
+		return nil, false
+	}
+	if len(s.Points) == 1 {
+		return nil, true
+	}
+	r2 := radius * radius
+	var cur *stroke
+	start := func(p Vec2, w float32) {
+		cur = &stroke{
+			Size:    s.Size,
+			Color:   s.Color,
+			Tags:    append([]string(nil), s.Tags...),
+			Eraser:  s.Eraser,
+			Square:  s.Square,
+			Layer:   s.Layer,
+			Opacity: s.Opacity,
+			Flow:    s.Flow,
+		}
+		if s.Widths != nil {
+			cur.Widths = []float32{}
+		}
+		cur.add(p, w)
+	}
+	finish := func() {
+		if cur != nil && len(cur.Points) > 1 {
+			cur.recomputeBounds()
+			pieces = append(pieces, cur)
+		}
+		cur = nil
+	}
+
+	if p := s.Points[0]; sqDist(p, c) > r2 {
+		start(p, s.widthAt(0))
+	}
+	for i := 0; i+1 < len(s.Points); i++ {
+		a, b := s.Points[i], s.Points[i+1]
+		wa, wb := s.widthAt(i), s.widthAt(i+1)
+		t0, t1, ok := segmentCircle(a, b, c, radius)
+		if !ok {
+			if cur == nil {
+				start(a, wa)
+			}
+			cur.add(b, wb)
+			continue
+		}
+		if t0 > 0 && cur != nil {
+			cur.add(lerpVec(a, b, t0), wa+(wb-wa)*float32(t0))
+		}
+		finish()
+		if t1 < 1 {
+			start(lerpVec(a, b, t1), wa+(wb-wa)*float32(t1))
+			cur.add(b, wb)
+		}
+	}
+	finish()
+	return pieces, true
+}
+
+// add appends a point, with its width when the stroke is tapered.
+func (s *stroke) add(p Vec2, w float32) {
+	s.Points = append(s.Points, p)
+	if s.Widths != nil {
+		s.Widths = append(s.Widths, w)
+	}
+}
+
+// segmentCircle returns the part of segment a-b, as parameters t0 <= t1
+// in [0, 1], that lies inside the circle around c. ok is false when the
+// segment stays outside.
+func segmentCircle(a, b, c Vec2, radius float64) (t0, t1 float64, ok bool) {
+	dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+	fx, fy := float64(a.X-c.X), float64(a.Y-c.Y)
+	qa := dx*dx + dy*dy
+	qb := 2 * (fx*dx + fy*dy)
+	qc := fx*fx + fy*fy - radius*radius
+	if qa == 0 {
+		return 0, 1, qc <= 0
+	}
+	disc := qb*qb - 4*qa*qc
+	if disc < 0 {
+		return 0, 0, false
+	}
+	root := math.Sqrt(disc)
+	t0 = (-qb - root) / (2 * qa)
+	t1 = (-qb + root) / (2 * qa)
+	if t1 < 0 || t0 > 1 {
+		return 0, 0, false
+	}
+	return max(t0, 0), min(t1, 1), true
+}
+
+func lerpVec(a, b Vec2, t float64) Vec2 {
+	return Vec2{X: a.X + (b.X-a.X)*float32(t), Y: a.Y + (b.Y-a.Y)*float32(t)}
+}
+
+func sqDist(a, b Vec2) float64 {
+	dx, dy := float64(a.X-b.X), float64(a.Y-b.Y)
+	return dx*dx + dy*dy
+}
+
+// splitErase removes the part of every visible stroke within the eraser
+// around pos, replacing each touched stroke with what is left of it. It
+// returns the world area to redraw, which is empty when nothing was hit.
+func (g *Game) splitErase(pos Vec2) image.Rectangle {
+	var dirty image.Rectangle
+	radius := g.worldSize(g.eraserSize) / 2
+	near := g.strokesNear(pos, radius)
+	kept := make([]*stroke, 0, len(g.strokes))
+	for _, s := range g.strokes {
+		if !near[s] || !g.strokeVisible(s) || g.eraseOnlyMine && !sameColor(s.Color, g.brushColor) {
+			kept = append(kept, s)
+			continue
+		}
+		pieces, touched := splitStroke(s, pos, radius+s.Size/2)
+		if !touched {
+			kept = append(kept, s)
+			continue
+		}
+		kept = append(kept, pieces...)
+		dirty = dirty.Union(s.paintBounds(g.smoothStrokes))
+		g.index.remove(s)
+		for _, p := range pieces {
+			g.index.insert(p)
+		}
+	}
+	g.strokes = kept
+	return dirty
+}
+
+func (g *Game) toggleSplitErase() {
+	g.splitStrokes = !g.splitStrokes
+}
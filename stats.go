@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// drawingStats summarizes the document for the statistics panel.
+type drawingStats struct {
+	strokes     int
+	erased      int
+	points      int
+	textBoxes   int
+	boundsW     int
+	boundsH     int
+	memoryBytes int
+}
+
+// computeStats walks the strokes on demand; it is only called while the
+// panel is open.
+func (g *Game) computeStats() drawingStats {
+	var st drawingStats
+	for _, s := range g.strokes {
+		st.strokes++
+		if s.Erased {
+			st.erased++
+		}
+		st.points += len(s.Points)
+	}
+	st.textBoxes = len(g.textBoxes)
+	if bounds, ok := g.drawingBounds(); ok {
+		st.boundsW = bounds.Dx()
+		st.boundsH = bounds.Dy()
+	}
+	// Points are two float32s; the canvas is RGBA8 on the GPU.
+	st.memoryBytes = st.points*8 + g.canvas.Bounds().Dx()*g.canvas.Bounds().Dy()*4
+	return st
+}
+
+func (g *Game) drawStatsPanel(dst *ebiten.Image) {
+	st := g.computeStats()
+	lines := []string{
+		"Statistics",
+		fmt.Sprintf("Strokes: %d (%d erased)", st.strokes, st.erased),
+		fmt.Sprintf("Points: %d", st.points),
+		fmt.Sprintf("Text boxes: %d", st.textBoxes),
+		fmt.Sprintf("Bounds: %d x %d", st.boundsW, st.boundsH),
+		fmt.Sprintf("Memory: ~%.1f MB", float64(st.memoryBytes)/(1<<20)),
+	}
+	w, _ := dst.Size()
+	panelW, lineH := 260, 24
+	x := w - panelW - 20
+	y := uiHeight + 20
+	vector.DrawFilledRect(dst, float32(x), float32(y), float32(panelW), float32(len(lines)*lineH+16), color.RGBA{30, 30, 30, 220}, false)
+	for i, line := range lines {
+		drawText(dst, line, x+12, y+28+i*lineH, color.White)
+	}
+}
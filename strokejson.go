@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// strokeJSONFormat identifies the public stroke export. Unlike .draft
+// project files it is meant for other programs, so its fields are only
+// ever added to, never renamed; see the README for the schema.
+const strokeJSONFormat = "draftit-strokes"
+
+type strokeJSONFile struct {
+	Format  string           `json:"format"`
+	Version int              `json:"version"`
+	Strokes []strokeJSONPath `json:"strokes"`
+}
+
+type strokeJSONPath struct {
+	Points  [][2]float32 `json:"points"`
+	Width   float64      `json:"width"`
+	Color   string       `json:"color"`
+	Opacity float64      `json:"opacity"`
+	Eraser  bool         `json:"eraser"`
+	Square  bool         `json:"square"`
+	Tags    []string     `json:"tags"`
+	// Widths is only present for tapered strokes.
+	Widths []float32 `json:"widths,omitempty"`
+	// Flow is only present for strokes below full flow.
+	Flow float64 `json:"flow,omitempty"`
+}
+
+func isStrokeJSONPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// strokeJSON collects the visible strokes in paint order, bottom layer
+// first.
+func (g *Game) strokeJSON() strokeJSONFile {
+	out := strokeJSONFile{Format: strokeJSONFormat, Version: 1, Strokes: []strokeJSONPath{}}
+	for _, s := range g.strokesByLayer() {
+		if !g.strokeVisible(s) || len(s.Points) == 0 || s.Fill != nil {
+			// Fills are pixel masks, which the path format cannot hold.
+			continue
+		}
+		p := strokeJSONPath{
+			Points:  make([][2]float32, len(s.Points)),
+			Width:   s.Size,
+			Color:   rgbHex(color.RGBAModel.Convert(s.Color).(color.RGBA)),
+			Opacity: s.alpha(),
+			Eraser:  s.Eraser,
+			Square:  s.Square,
+			Tags:    append([]string{}, s.Tags...),
+			Widths:  s.Widths,
+		}
+		if f := s.flowRate(); f < 1 {
+			p.Flow = f
+		}
+		for i, pt := range s.Points {
+			p.Points[i] = [2]float32{pt.X, pt.Y}
+		}
+		out.Strokes = append(out.Strokes, p)
+	}
+	return out
+}
+
+func writeStrokeJSON(path string, f strokeJSONFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,70 @@
+package main
+
+// strokeTagPresets are the tags offered in the toolbar and palette.
+var strokeTagPresets = []string{"sketch", "ink", "color"}
+
+func (s *stroke) hasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// strokeVisible reports whether a stroke is drawn, exported, and erasable:
+// it must not be erased, sit on a hidden layer, or carry a hidden tag.
+func (g *Game) strokeVisible(s *stroke) bool {
+	if s.Erased || g.layerHidden(s.Layer) {
+		return false
+	}
+	for _, t := range s.Tags {
+		if g.hiddenTags[t] {
+			return false
+		}
+	}
+	return true
+}
+
+// cycleActiveTag steps the tag applied to new strokes through the presets
+// and back to untagged.
+func (g *Game) cycleActiveTag() {
+	next := ""
+	for i, t := range strokeTagPresets {
+		if t == g.activeTag && i+1 < len(strokeTagPresets) {
+			next = strokeTagPresets[i+1]
+		}
+	}
+	if g.activeTag == "" {
+		next = strokeTagPresets[0]
+	}
+	g.activeTag = next
+}
+
+func (g *Game) activeTagLabel() string {
+	if g.activeTag == "" {
+		return "Tag: none"
+	}
+	return "Tag: " + g.activeTag
+}
+
+func (g *Game) toggleTagVisibility(tag string) {
+	if g.hiddenTags == nil {
+		g.hiddenTags = map[string]bool{}
+	}
+	g.hiddenTags[tag] = !g.hiddenTags[tag]
+	g.rebuildCanvas()
+}
+
+func (g *Game) tagCommands() []command {
+	var cmds []command
+	for _, tag := range strokeTagPresets {
+		tag := tag
+		state := "Shown"
+		if g.hiddenTags[tag] {
+			state = "Hidden"
+		}
+		cmds = append(cmds, command{name: "Tag \"" + tag + "\": " + state, run: func() { g.toggleTagVisibility(tag) }})
+	}
+	return cmds
+}
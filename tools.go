@@ -0,0 +1,227 @@
+package main
+
+import (
+	"image/color"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// toolInput is the per-frame pointer state handed to the active tool.
+type toolInput struct {
+	mx, my      int
+	left, right bool
+	justClicked bool
+}
+
+// Tool is a canvas tool. handleMainInput tracks the button state and calls
+// OnPress when a press reaches the canvas, OnDrag while it is held,
+// OnRelease once when it ends, and OnIdle on every other frame. Draw
+// renders cursor feedback in screen space.
+type Tool interface {
+	Name() string
+	OnPress(g *Game, in toolInput)
+	OnDrag(g *Game, in toolInput)
+	OnRelease(g *Game, in toolInput)
+	OnIdle(g *Game, in toolInput)
+	Draw(g *Game, screen *ebiten.Image)
+}
+
+func defaultTools() map[toolMode]Tool {
+	return map[toolMode]Tool{
+		modeDraw:        brushTool{},
+		modePixelErase:  pixelEraserTool{},
+		modeStrokeErase: strokeEraserTool{},
+		modeText:        textTool{},
+		modeArtboard:    artboardTool{},
+		modeEyedropper:  eyedropperTool{},
+		modeFill:        fillTool{},
+	}
+}
+
+// cycleTool switches to the next registered tool in mode order, wrapping
+// around after the last.
+func (g *Game) cycleTool() {
+	modes := make([]toolMode, 0, len(g.tools))
+	for m := range g.tools {
+		modes = append(modes, m)
+	}
+	if len(modes) == 0 {
+		return
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i] < modes[j] })
+	next := modes[0]
+	for _, m := range modes {
+		if m > g.mode {
+			next = m
+			break
+		}
+	}
+	g.setMode(next)
+}
+
+// dispatchTool routes this frame's pointer state to the active tool.
+func (g *Game) dispatchTool(in toolInput) {
+	tool, ok := g.tools[g.mode]
+	if !ok {
+		return
+	}
+	pressed := in.left || in.right
+	switch {
+	case pressed && !g.toolHeld:
+		tool.OnPress(g, in)
+	case pressed:
+		tool.OnDrag(g, in)
+	case g.toolHeld:
+		tool.OnRelease(g, in)
+	default:
+		tool.OnIdle(g, in)
+	}
+	g.toolHeld = pressed
+}
+
+// worldSize converts a brush or eraser slider size to world units. Sizes
+// are world units unless screenSizes is on, when they are screen pixels
+// and strokes drawn zoomed in come out finer.
+func (g *Game) worldSize(size float64) float64 {
+	if g.screenSizes {
+		return size / g.zoom
+	}
+	return size
+}
+
+func (g *Game) sizeLockLabel() string {
+	if g.screenSizes {
+		return "Screen"
+	}
+	return "World"
+}
+
+func (g *Game) toggleSizeLock() {
+	g.screenSizes = !g.screenSizes
+}
+
+// cursorOnCanvas reports whether a size preview should follow the cursor:
+// it must be over the canvas with no dialog or popup in front.
+func (g *Game) cursorOnCanvas() bool {
+	_, my := ebiten.CursorPosition()
+	if my <= g.toolbarHeight() {
+		return false
+	}
+	return !g.save.visible && !g.confirm.visible && !g.newDoc.visible && !g.pageSize.visible && !g.palette.visible && !g.picker.visible
+}
+
+// drawBrushCursor outlines the brush footprint at the cursor, scaled by
+// the zoom so it matches what a stroke will cover.
+func drawBrushCursor(g *Game, screen *ebiten.Image) {
+	if !g.cursorOnCanvas() {
+		return
+	}
+	mx, my := ebiten.CursorPosition()
+	radius := float32(g.worldSize(g.brushSize) / 2 * g.zoom)
+	vector.StrokeCircle(screen, float32(mx), float32(my), radius, 1, color.RGBA{200, 200, 200, 200}, true)
+}
+
+func drawEraserCursor(g *Game, screen *ebiten.Image) {
+	if !g.cursorOnCanvas() {
+		return
+	}
+	mx, my := ebiten.CursorPosition()
+	radius := float32(g.worldSize(g.eraserSize) / 2 * g.zoom)
+	if g.mode == modePixelErase && g.squareEraser {
+		vector.StrokeRect(screen, float32(mx)-radius, float32(my)-radius, 2*radius, 2*radius, 1, color.RGBA{200, 200, 200, 200}, false)
+		return
+	}
+	vector.StrokeCircle(screen, float32(mx), float32(my), radius, 1, color.RGBA{200, 200, 200, 200}, true)
+}
+
+type brushTool struct{}
+
+func (brushTool) Name() string { return "Brush" }
+
+func (brushTool) OnPress(g *Game, in toolInput) { brushTool{}.OnDrag(g, in) }
+
+func (brushTool) OnDrag(g *Game, in toolInput) {
+	clr := g.brushColor
+	if !in.left && in.right {
+		clr = g.secondColor
+	}
+	g.handleStrokeDrawing(in.mx, in.my, true, g.worldSize(g.brushSize), clr, g.brushOpacity)
+}
+
+func (brushTool) OnRelease(g *Game, in toolInput) {
+	g.handleStrokeDrawing(in.mx, in.my, false, g.worldSize(g.brushSize), g.brushColor, g.brushOpacity)
+}
+
+func (brushTool) OnIdle(g *Game, in toolInput) { brushTool{}.OnRelease(g, in) }
+
+func (brushTool) Draw(g *Game, screen *ebiten.Image) { drawBrushCursor(g, screen) }
+
+type pixelEraserTool struct{}
+
+func (pixelEraserTool) Name() string { return "Pixel Eraser" }
+
+func (pixelEraserTool) OnPress(g *Game, in toolInput) { pixelEraserTool{}.OnDrag(g, in) }
+
+// OnDrag erases with the left button only; holding just the right one
+// ends the stroke.
+func (pixelEraserTool) OnDrag(g *Game, in toolInput) {
+	g.handleStrokeDrawing(in.mx, in.my, in.left, g.worldSize(g.eraserSize), g.bgColor, 1)
+}
+
+func (pixelEraserTool) OnRelease(g *Game, in toolInput) {
+	g.handleStrokeDrawing(in.mx, in.my, false, g.worldSize(g.eraserSize), g.bgColor, 1)
+}
+
+func (pixelEraserTool) OnIdle(g *Game, in toolInput) { pixelEraserTool{}.OnRelease(g, in) }
+
+func (pixelEraserTool) Draw(g *Game, screen *ebiten.Image) { drawEraserCursor(g, screen) }
+
+type strokeEraserTool struct{}
+
+func (strokeEraserTool) Name() string { return "Stroke Eraser" }
+
+func (strokeEraserTool) OnPress(g *Game, in toolInput) { g.handleStrokeErase(in.mx, in.my, in.left) }
+
+func (strokeEraserTool) OnDrag(g *Game, in toolInput) { g.handleStrokeErase(in.mx, in.my, in.left) }
+
+func (strokeEraserTool) OnRelease(*Game, toolInput) {}
+
+func (strokeEraserTool) OnIdle(*Game, toolInput) {}
+
+func (strokeEraserTool) Draw(g *Game, screen *ebiten.Image) { drawEraserCursor(g, screen) }
+
+type textTool struct{}
+
+func (textTool) Name() string { return "Text" }
+
+func (textTool) OnPress(g *Game, in toolInput) {
+	g.handleTextTools(in.mx, in.my, in.left, in.justClicked)
+}
+
+func (textTool) OnDrag(g *Game, in toolInput) { g.handleTextTools(in.mx, in.my, in.left, false) }
+
+func (textTool) OnRelease(g *Game, in toolInput) { g.handleTextTools(in.mx, in.my, false, false) }
+
+func (textTool) OnIdle(g *Game, in toolInput) { g.handleTextTools(in.mx, in.my, false, false) }
+
+func (textTool) Draw(*Game, *ebiten.Image) {}
+
+type artboardTool struct{}
+
+func (artboardTool) Name() string { return "Artboard" }
+
+func (artboardTool) OnPress(g *Game, in toolInput) {
+	g.handleArtboardTool(in.mx, in.my, in.left, in.justClicked)
+}
+
+func (artboardTool) OnDrag(g *Game, in toolInput) { g.handleArtboardTool(in.mx, in.my, in.left, false) }
+
+func (artboardTool) OnRelease(g *Game, in toolInput) {
+	g.handleArtboardTool(in.mx, in.my, false, false)
+}
+
+func (artboardTool) OnIdle(g *Game, in toolInput) { g.handleArtboardTool(in.mx, in.my, false, false) }
+
+func (artboardTool) Draw(*Game, *ebiten.Image) {}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// recordingTool logs each callback with the buttons it saw.
+type recordingTool struct {
+	calls []string
+}
+
+func (*recordingTool) Name() string { return "Recorder" }
+
+func (r *recordingTool) log(event string, in toolInput) {
+	switch {
+	case in.left:
+		event += " left"
+	case in.right:
+		event += " right"
+	}
+	r.calls = append(r.calls, event)
+}
+
+func (r *recordingTool) OnPress(_ *Game, in toolInput)   { r.log("press", in) }
+func (r *recordingTool) OnDrag(_ *Game, in toolInput)    { r.log("drag", in) }
+func (r *recordingTool) OnRelease(_ *Game, in toolInput) { r.log("release", in) }
+func (r *recordingTool) OnIdle(_ *Game, in toolInput)    { r.log("idle", in) }
+func (*recordingTool) Draw(*Game, *ebiten.Image)         {}
+
+func TestDispatchTool(t *testing.T) {
+	rec := &recordingTool{}
+	g := &Game{mode: modeDraw, tools: map[toolMode]Tool{modeDraw: rec}}
+	frames := []toolInput{
+		{},
+		{left: true, justClicked: true},
+		{left: true},
+		{left: true},
+		{},
+		{},
+		{right: true},
+		{right: true},
+		{},
+	}
+	for _, in := range frames {
+		g.dispatchTool(in)
+	}
+	want := []string{
+		"idle",
+		"press left",
+		"drag left",
+		"drag left",
+		"release",
+		"idle",
+		"press right",
+		"drag right",
+		"release",
+	}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("calls = %q, want %q", rec.calls, want)
+	}
+	if g.toolHeld {
+		t.Error("toolHeld still set after release")
+	}
+}
+
+func TestDispatchToolUnregisteredMode(t *testing.T) {
+	rec := &recordingTool{}
+	g := &Game{mode: modeText, tools: map[toolMode]Tool{modeDraw: rec}}
+	g.dispatchTool(toolInput{left: true, justClicked: true})
+	if len(rec.calls) != 0 {
+		t.Errorf("tool for another mode was called: %q", rec.calls)
+	}
+}
+
+// newToolGame returns a Game set up like NewGame, with the default tools
+// and a small canvas around the origin, but without reading the config.
+// Image operations before the game loop starts are queued, so the tools
+// can draw without a window.
+func newToolGame() *Game {
+	initFont()
+	g := &Game{
+		canvas:       ebiten.NewImage(512, 512),
+		canvasOrigin: vec2d{X: -256, Y: -256},
+		strokes:      []*stroke{},
+		layers:       defaultLayers(),
+		mode:         modeDraw,
+		currentMode:  modeDraw,
+		brushSize:    10,
+		eraserSize:   20,
+		textSize:     24,
+		brushOpacity: 1,
+		brushFlow:    1,
+		gridSize:     32,
+		textBoxes:    []textBox{},
+		selectedText: -1,
+		editingText:  -1,
+		brushColor:   color.White,
+		bgColor:      color.Black,
+		zoom:         1,
+		secondColor:  color.RGBA{128, 128, 128, 255},
+		tools:        defaultTools(),
+	}
+	g.setupUI()
+	g.recordState()
+	return g
+}
+
+// drag presses a button at the first point, holds it through the rest,
+// and releases it over the last.
+func drag(g *Game, right bool, pts ...image.Point) {
+	for i, p := range pts {
+		g.dispatchTool(toolInput{mx: p.X, my: p.Y, left: !right, right: right, justClicked: i == 0 && !right})
+	}
+	last := pts[len(pts)-1]
+	g.dispatchTool(toolInput{mx: last.X, my: last.Y})
+}
+
+// The tool tests below pin the behavior of the mode switch the tools
+// replaced: the brush draws with either button, the right one in the
+// secondary color, and the other tools answer only the left button.
+
+func TestBrushTool(t *testing.T) {
+	g := newToolGame()
+	drag(g, false, image.Pt(10, 10), image.Pt(40, 10), image.Pt(70, 20))
+	if len(g.strokes) != 1 {
+		t.Fatalf("left drag made %d strokes, want 1", len(g.strokes))
+	}
+	s := g.strokes[0]
+	want := []Vec2{{X: 10, Y: 10}, {X: 40, Y: 10}, {X: 70, Y: 20}}
+	if !reflect.DeepEqual(s.Points, want) {
+		t.Errorf("points = %v, want %v", s.Points, want)
+	}
+	if s.Eraser || s.Size != 10 || !sameColor(s.Color, g.brushColor) {
+		t.Errorf("stroke = eraser %v, size %v, color %v; want a size 10 brush stroke in the brush color", s.Eraser, s.Size, s.Color)
+	}
+	if len(g.undoStack) != 2 {
+		t.Errorf("undo stack has %d entries, want 2", len(g.undoStack))
+	}
+
+	drag(g, true, image.Pt(10, 80), image.Pt(60, 80))
+	if len(g.strokes) != 2 {
+		t.Fatalf("right drag made %d strokes in total, want 2", len(g.strokes))
+	}
+	if !sameColor(g.strokes[1].Color, g.secondColor) {
+		t.Errorf("right drag color = %v, want the secondary color", g.strokes[1].Color)
+	}
+}
+
+func TestPixelEraserTool(t *testing.T) {
+	g := newToolGame()
+	g.setMode(modePixelErase)
+	drag(g, false, image.Pt(10, 10), image.Pt(50, 10))
+	if len(g.strokes) != 1 {
+		t.Fatalf("left drag made %d strokes, want 1", len(g.strokes))
+	}
+	if s := g.strokes[0]; !s.Eraser || s.Square || s.Size != 20 || len(s.Points) != 2 {
+		t.Errorf("stroke = eraser %v, square %v, size %v, %d points; want a round size 20 eraser stroke of 2 points", s.Eraser, s.Square, s.Size, len(s.Points))
+	}
+
+	drag(g, true, image.Pt(10, 80), image.Pt(50, 80))
+	if len(g.strokes) != 1 {
+		t.Errorf("right drag erased: %d strokes, want 1", len(g.strokes))
+	}
+}
+
+func TestStrokeEraserTool(t *testing.T) {
+	g := newToolGame()
+	top := zigzag(0, 10, 10, color.White)
+	bottom := zigzag(0, 100, 10, color.White)
+	g.strokes = []*stroke{top, bottom}
+	g.setMode(modeStrokeErase)
+
+	drag(g, true, image.Pt(15, 100), image.Pt(20, 100))
+	if bottom.Erased {
+		t.Error("right drag erased a stroke")
+	}
+	drag(g, false, image.Pt(15, 12), image.Pt(20, 12))
+	if !top.Erased {
+		t.Error("stroke under the eraser was not erased")
+	}
+	if bottom.Erased {
+		t.Error("stroke away from the eraser was erased")
+	}
+	if len(g.strokes) != 2 {
+		t.Errorf("erasing changed the stroke count to %d", len(g.strokes))
+	}
+}
+
+func TestTextTool(t *testing.T) {
+	g := newToolGame()
+	g.setMode(modeText)
+	drag(g, true, image.Pt(200, 60))
+	if len(g.textBoxes) != 0 {
+		t.Fatalf("right click added %d text boxes", len(g.textBoxes))
+	}
+	drag(g, false, image.Pt(50, 60))
+	if len(g.textBoxes) != 1 {
+		t.Fatalf("click added %d text boxes, want 1", len(g.textBoxes))
+	}
+	tb := g.textBoxes[0]
+	if tb.Position != (Vec2{X: 50, Y: 60}) || tb.Size != 24 || tb.Text != "" {
+		t.Errorf("text box = %+v, want an empty size 24 box at (50, 60)", tb)
+	}
+	if g.editingText != 0 || g.selectedText != 0 {
+		t.Errorf("editing %d, selected %d; want the new box for both", g.editingText, g.selectedText)
+	}
+
+	// Clicking the box again selects it for dragging instead of adding one.
+	drag(g, false, image.Pt(52, 64), image.Pt(82, 94))
+	if len(g.textBoxes) != 1 {
+		t.Fatalf("clicking a text box added one: %d boxes", len(g.textBoxes))
+	}
+	if got := g.textBoxes[0].Position; got != (Vec2{X: 80, Y: 90}) {
+		t.Errorf("dragged box to %v, want (80, 90)", got)
+	}
+}
+
+func TestWorldSize(t *testing.T) {
+	for _, tc := range []struct {
+		screenSizes bool
+		zoom        float64
+		want        float64
+	}{
+		{false, 0.5, 12},
+		{false, 1, 12},
+		{false, 4, 12},
+		{true, 0.5, 24},
+		{true, 1, 12},
+		{true, 4, 3},
+	} {
+		g := &Game{screenSizes: tc.screenSizes, zoom: tc.zoom}
+		if got := g.worldSize(12); got != tc.want {
+			t.Errorf("worldSize(12) with screenSizes=%v zoom=%v = %v, want %v", tc.screenSizes, tc.zoom, got, tc.want)
+		}
+	}
+}
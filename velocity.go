@@ -0,0 +1,58 @@
+package main
+
+// Ebiten reports no pen pressure, so tapered strokes take their width from
+// how fast the cursor moves: slow strokes stay at the brush size and fast
+// ones thin toward velocityMinFactor of it.
+const (
+	// velocityFullSpeed is the cursor speed, in screen pixels per tick,
+	// at which a stroke reaches its thinnest.
+	velocityFullSpeed = 40
+	velocityMinFactor = 0.25
+	// velocitySmoothing is how far each new width moves toward its target,
+	// so a jerky drag does not produce a beaded line.
+	velocitySmoothing = 0.35
+)
+
+// velocityWidth returns the width for a point captured dist screen pixels
+// after one drawn at prev.
+func velocityWidth(size float64, prev float32, dist float64) float32 {
+	f := max(velocityMinFactor, min(1, 1-dist/velocityFullSpeed))
+	target := float32(size * f)
+	return prev + (target-prev)*velocitySmoothing
+}
+
+// widthAt returns the width at point i, which is Size unless the stroke
+// carries per-point widths.
+func (s *stroke) widthAt(i int) float32 {
+	if s.Widths == nil {
+		return float32(s.Size)
+	}
+	return s.Widths[i]
+}
+
+// appendTapered adds a stroke with per-point widths, one segment at a
+// time so each can have its own width. Smoothed segments interpolate the
+// width along the curve.
+func (b *strokeBatch) appendTapered(s *stroke, xf func(Vec2) Vec2, scale float64) {
+	k := float32(scale)
+	var curve []Vec2
+	for i := 0; i+1 < len(s.Points); i++ {
+		wa, wb := s.widthAt(i), s.widthAt(i+1)
+		if !b.smooth {
+			b.appendSegment(xf(s.Points[i]), xf(s.Points[i+1]), (wa+wb)/2*k)
+			continue
+		}
+		curve = smoothSegment(curve[:0], s.Points, i)
+		prev := xf(s.Points[i])
+		for j, q := range curve {
+			t := (float32(j) + 0.5) / float32(len(curve))
+			p := xf(q)
+			b.appendSegment(prev, p, (wa+(wb-wa)*t)*k)
+			prev = p
+		}
+	}
+}
+
+func (g *Game) toggleVelocityWidth() {
+	g.velocityWidth = !g.velocityWidth
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// dirWritable reports whether dir is a directory that might be written.
+// Windows folders often carry a read-only attribute that does not stop
+// files being created in them, and ACLs cannot be checked without
+// writing, so a folder that cannot be written shows up as an error when
+// the file is saved.
+func dirWritable(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// accessWrite is access(2)'s W_OK, which package syscall does not name.
+const accessWrite = 0x2
+
+// dirWritable asks the system whether files can be created in dir without
+// writing anything there. access(2) accounts for the real user and group,
+// ACLs, and read-only mounts; anything it misses shows up as an error when
+// the file is saved.
+func dirWritable(dir string) bool {
+	return syscall.Access(dir, accessWrite) == nil
+}